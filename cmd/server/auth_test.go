@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func authTestRouter(keys ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	api := router.Group("", apiKeyMiddleware(keys))
+	api.POST("/api/reddit/extract", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	router := authTestRouter("k1", "k2")
+
+	cases := []struct {
+		name, header, value string
+		want                int
+	}{
+		{"missing", "", "", http.StatusUnauthorized},
+		{"bearer", "Authorization", "Bearer k2", http.StatusOK},
+		{"bearer lowercase", "Authorization", "bearer k1", http.StatusOK},
+		{"x-api-key", "X-API-Key", "k1", http.StatusOK},
+		{"wrong key", "X-API-Key", "nope", http.StatusUnauthorized},
+		{"basic auth", "Authorization", "Basic azE6", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/reddit/extract", nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("healthz status = %d, want 200 without a key", rec.Code)
+	}
+}
+
+func TestAPIKeyMiddlewareOpenWithoutKeys(t *testing.T) {
+	rec := httptest.NewRecorder()
+	authTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/reddit/extract", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 with no keys configured", rec.Code)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	got := parseList(" https://a.example.com, ,https://b.example.com")
+	if len(got) != 2 || got[0] != "https://a.example.com" || got[1] != "https://b.example.com" {
+		t.Errorf("parseList = %q", got)
+	}
+}
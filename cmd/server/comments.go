@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+// commentsHandler serves only the comments of a post, which is cheaper than
+// the extract endpoint when the post fields are already known, for example
+// when refreshing a thread.
+//
+// Query parameters: url (required), top, keeping only the n highest-scored
+// top-level comments without their replies, and comments=flat, returning
+// the pre-order list of extractor.FlattenComments instead of the tree.
+//
+// With since, a Unix timestamp, the response is instead the
+// extractor.NewComments posted after it, for polling a live thread; pass
+// its latest field as since on the next poll.
+func commentsHandler(ext *extractor.Extractor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		postURL := c.Query("url")
+		if err := ext.ValidateRedditURL(postURL); err != nil {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		var opts []extractor.ExtractOption
+		if raw := c.Query("top"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 {
+				renderJSON(c, http.StatusBadRequest, apiResponse{
+					Success: false,
+					Error:   "top must be a positive integer",
+				})
+				return
+			}
+			opts = append(opts, extractor.TopComments(n, false))
+		}
+
+		var since int64
+		raw, polling := c.GetQuery("since")
+		if polling {
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || n < 0 {
+				renderJSON(c, http.StatusBadRequest, apiResponse{
+					Success: false,
+					Error:   "since must be a unix timestamp",
+				})
+				return
+			}
+			since = n
+		}
+		if polling && len(opts) > 0 {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   "top cannot be combined with since",
+			})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		var comments []extractor.Comment
+		var newComments *extractor.NewComments
+		var err error
+		if polling {
+			newComments, err = ext.ExtractNewComments(ctx, postURL, since, opts...)
+		} else {
+			comments, err = ext.ExtractRedditComments(ctx, postURL, opts...)
+		}
+		recordElapsed(c, start)
+		if err != nil {
+			var validationErr extractor.ValidationError
+			if errors.As(err, &validationErr) {
+				renderJSON(c, http.StatusBadRequest, apiResponse{
+					Success: false,
+					Error:   validationErr.Error(),
+				})
+				return
+			}
+			renderJSON(c, upstreamErrorStatus(c, err), apiResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		var data interface{} = comments
+		switch {
+		case polling:
+			data = newComments
+		case c.Query("comments") == "flat":
+			data = extractor.FlattenComments(comments)
+		}
+		renderJSON(c, http.StatusOK, apiResponse{Success: true, Data: data})
+	}
+}
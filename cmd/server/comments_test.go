@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const commentsPostFixture = `[
+	{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Fixture post"}}]}},
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t1", "data": {"id": "a", "body": "low", "score": 1, "replies": {
+			"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"id": "b", "body": "reply", "score": 0, "replies": ""}}]}
+		}}},
+		{"kind": "t1", "data": {"id": "c", "body": "high", "score": 9, "created_utc": 1700000000.0, "replies": ""}}
+	]}}
+]`
+
+func TestCommentsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/reddit/comments", commentsHandler(sequenceExtractor(commentsPostFixture)))
+	const postURL = "https%3A%2F%2Fwww.reddit.com%2Fr%2Fgolang%2Fcomments%2Fabc123%2Fx%2F"
+
+	cases := []struct {
+		query  string
+		status int
+		want   string
+	}{
+		{"url=" + postURL, http.StatusOK, `{"success":true,"data":[{"id":"a","body":"low","score":1,"replies":[{"id":"b","body":"reply","score":0}]},{"id":"c","body":"high","score":9,"created_utc":1700000000}]}`},
+		{"url=" + postURL + "&top=1", http.StatusOK, `{"success":true,"data":[{"id":"c","body":"high","score":9,"created_utc":1700000000}]}`},
+		{"url=" + postURL + "&comments=flat", http.StatusOK, `{"success":true,"data":[{"id":"a","body":"low","score":1,"depth":0,"parent_index":-1},{"id":"b","body":"reply","score":0,"depth":1,"parent_index":0},{"id":"c","body":"high","score":9,"created_utc":1700000000,"depth":0,"parent_index":-1}]}`},
+		{"url=" + postURL + "&since=0", http.StatusOK, `{"success":true,"data":{"comments":[{"id":"c","body":"high","score":9,"created_utc":1700000000}],"latest":1700000000}}`},
+		{"url=" + postURL + "&since=soon", http.StatusBadRequest, `{"success":false,"error":"since must be a unix timestamp"}`},
+		{"url=" + postURL + "&since=1&top=1", http.StatusBadRequest, `{"success":false,"error":"top cannot be combined with since"}`},
+		{"url=" + postURL + "&top=0", http.StatusBadRequest, `{"success":false,"error":"top must be a positive integer"}`},
+		{"url=https%3A%2F%2Fwww.reddit.com%2Fr%2Fgolang%2F", http.StatusBadRequest, `{"success":false,"error":"invalid reddit post url"}`},
+		{"", http.StatusBadRequest, `{"success":false,"error":"url is required"}`},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/reddit/comments?"+tc.query, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.query, rec.Code, tc.status)
+		}
+		if got := strings.TrimSpace(rec.Body.String()); got != tc.want {
+			t.Errorf("%s: body = %s, want %s", tc.query, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	corsAllowMethods  = "GET, POST, OPTIONS"
+	corsAllowHeaders  = "Content-Type, Authorization, X-API-Key, X-Request-ID"
+	corsExposeHeaders = "X-Request-ID"
+	corsMaxAge        = "600"
+)
+
+// corsMiddleware lets browsers on the given origins call the API. "*"
+// allows any origin. Requests from other origins get no CORS headers, so
+// browsers keep them same-origin only, and preflight OPTIONS requests are
+// answered here without reaching the routes.
+func corsMiddleware(origins []string) gin.HandlerFunc {
+	allowed := newOriginSet(origins)
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || !allowed.allows(origin) {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Expose-Headers", corsExposeHeaders)
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", corsAllowMethods)
+			c.Header("Access-Control-Allow-Headers", corsAllowHeaders)
+			c.Header("Access-Control-Max-Age", corsMaxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// originSet holds the browser origins allowed to call the API, as given to
+// -cors-origins.
+type originSet map[string]bool
+
+func newOriginSet(origins []string) originSet {
+	set := make(originSet, len(origins))
+	for _, origin := range origins {
+		set[strings.ToLower(strings.TrimRight(origin, "/"))] = true
+	}
+	return set
+}
+
+// allows reports whether origin is in the set, or the set holds "*".
+func (s originSet) allows(origin string) bool {
+	return s["*"] || s[strings.ToLower(origin)]
+}
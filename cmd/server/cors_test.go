@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func corsTestRouter(origins ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(corsMiddleware(origins))
+	router.POST("/api/reddit/extract", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	router := corsTestRouter("https://app.example.com")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/reddit/extract", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("status = %d, allow origin = %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	req = httptest.NewRequest(http.MethodOptions, "/api/reddit/extract", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("preflight status = %d, want 204", rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Methods") == "" || rec.Header().Get("Access-Control-Allow-Headers") == "" {
+		t.Errorf("preflight headers missing: %v", rec.Header())
+	}
+}
+
+func TestCORSOtherOrigin(t *testing.T) {
+	router := corsTestRouter("https://app.example.com")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/reddit/extract", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("allow origin = %q, want none", got)
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/reddit/extract", nil)
+	req.Header.Set("Origin", "https://anything.example.com")
+	rec := httptest.NewRecorder()
+	corsTestRouter("*").ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://anything.example.com" {
+		t.Errorf("allow origin = %q", got)
+	}
+}
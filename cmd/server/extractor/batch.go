@@ -0,0 +1,65 @@
+package extractor
+
+import (
+	"context"
+	"sync"
+)
+
+// batchConcurrency bounds how many posts of a batch are extracted at once.
+const batchConcurrency = 4
+
+// PostResult is the outcome of extracting a single URL of a batch.
+type PostResult struct {
+	URL  string
+	Post *RedditPost
+	Err  error
+}
+
+// ExtractRedditPosts extracts each URL using the default Extractor.
+func ExtractRedditPosts(ctx context.Context, urls []string, opts ...ExtractOption) []PostResult {
+	return defaultExtractor.ExtractRedditPosts(ctx, urls, opts...)
+}
+
+// ExtractRedditPosts extracts each URL concurrently, applying opts to each
+// extraction, and returns one result per URL in input order. By default a
+// failing URL only affects its own result; with StopOnError the first
+// failure cancels the extractions still running or waiting, whose results
+// then carry the context error.
+func (e *Extractor) ExtractRedditPosts(ctx context.Context, urls []string, opts ...ExtractOption) []PostResult {
+	stop := func() {}
+	if applyExtractOptions(opts).stopOnError {
+		ctx, stop = context.WithCancel(ctx)
+		defer stop()
+	}
+
+	results := make([]PostResult, len(urls))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		results[i].URL = u
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			// An invalid URL fails without waiting for a slot, so its
+			// failure stops the batch however long the others take.
+			if err := e.ValidateRedditURL(u); err != nil {
+				results[i].Err = err
+				stop()
+				return
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].Err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			results[i].Post, results[i].Err = e.ExtractRedditPostWithOptions(ctx, u, opts...)
+			if results[i].Err != nil {
+				stop()
+			}
+		}(i, u)
+	}
+	wg.Wait()
+	return results
+}
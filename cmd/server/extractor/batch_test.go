@@ -0,0 +1,89 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExtractRedditPostsPreservesOrder(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	urls := []string{
+		"https://www.reddit.com/r/golang/comments/abc123/one/",
+		"",
+		"https://www.reddit.com/r/golang/comments/def456/two/",
+	}
+	results := e.ExtractRedditPosts(context.Background(), urls)
+	if len(results) != len(urls) {
+		t.Fatalf("got %d results, want %d", len(results), len(urls))
+	}
+	for i, res := range results {
+		if res.URL != urls[i] {
+			t.Errorf("result %d url = %q, want %q", i, res.URL, urls[i])
+		}
+	}
+	if results[0].Err != nil || results[0].Post == nil {
+		t.Errorf("result 0: unexpected failure: %v", results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("result 1: expected validation error for empty url")
+	}
+	if results[2].Err != nil || results[2].Post.Title != "Fixture post" {
+		t.Errorf("result 2: unexpected result: %+v", results[2])
+	}
+}
+
+func TestExtractRedditPostsCollectsAll(t *testing.T) {
+	e := mustNewExtractor(WithHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		time.Sleep(10 * time.Millisecond)
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})}))
+
+	results := e.ExtractRedditPosts(context.Background(), batchURLsWithFailure)
+	for i, res := range results {
+		if failed := res.Err != nil; failed != (i == 1) {
+			t.Errorf("result %d: err = %v", i, res.Err)
+		}
+	}
+}
+
+func TestExtractRedditPostsStopOnError(t *testing.T) {
+	// Posts never load, so only the cancellation triggered by the invalid
+	// URL lets the batch finish.
+	e := mustNewExtractor(WithHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results := e.ExtractRedditPosts(ctx, batchURLsWithFailure, StopOnError())
+	if ctx.Err() != nil {
+		t.Fatal("batch ran until the deadline instead of stopping")
+	}
+	if err := results[1].Err; err == nil || errors.Is(err, context.Canceled) {
+		t.Errorf("result 1: err = %v, want its own failure", err)
+	}
+	for _, i := range []int{0, 2, 3, 4, 5} {
+		if !errors.Is(results[i].Err, context.Canceled) {
+			t.Errorf("result %d: err = %v, want context.Canceled", i, results[i].Err)
+		}
+	}
+}
+
+// batchURLsWithFailure holds more URLs than batchConcurrency, the second of
+// which fails validation.
+var batchURLsWithFailure = []string{
+	"https://www.reddit.com/r/golang/comments/abc123/one/",
+	"not a reddit url",
+	"https://www.reddit.com/r/golang/comments/abc124/two/",
+	"https://www.reddit.com/r/golang/comments/abc125/three/",
+	"https://www.reddit.com/r/golang/comments/abc126/four/",
+	"https://www.reddit.com/r/golang/comments/abc127/five/",
+}
@@ -0,0 +1,136 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitOpenError is returned without contacting Reddit while the circuit
+// breaker configured by WithCircuitBreaker is open.
+type CircuitOpenError struct {
+	// RetryAfter is the time left until the breaker lets a probe through.
+	RetryAfter time.Duration
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("reddit circuit breaker open after repeated upstream failures, retry in %v", e.RetryAfter.Round(time.Second))
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker fails requests fast once Reddit keeps failing. It opens
+// after threshold consecutive failures, the first and last at most window
+// apart, and stays open for cooldown. It then turns half-open and lets a
+// single probe through: a success closes it, a failure opens it again.
+type circuitBreaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	clock     Clock
+
+	mu           sync.Mutex
+	state        circuitState
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	probing      bool
+	probeStarted time.Time
+}
+
+// allow reports whether a request may be sent, returning a
+// CircuitOpenError if not, and whether the request is the half-open probe.
+// Every allowed request must be followed by a call to record with that
+// flag. Requests turned away while a probe is in flight are told to retry
+// after the cooldown counted from the probe's start, since a failed probe
+// opens the circuit again for that long, and after at least a second.
+func (b *circuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	if b.state == circuitOpen {
+		if wait := b.openedAt.Add(b.cooldown).Sub(now); wait > 0 {
+			return false, CircuitOpenError{RetryAfter: wait}
+		}
+		b.state = circuitHalfOpen
+	}
+	if b.state == circuitHalfOpen {
+		if b.probing {
+			return false, CircuitOpenError{RetryAfter: max(b.probeStarted.Add(b.cooldown).Sub(now), time.Second)}
+		}
+		b.probing = true
+		b.probeStarted = now
+		return true, nil
+	}
+	return false, nil
+}
+
+// record counts the outcome of an allowed request, probe being what allow
+// returned for it. Requests that ended because their own context did are
+// neither successes nor failures. Only the probe decides a circuit that is
+// not closed; the outcome of a request allowed before the circuit opened
+// no longer counts.
+func (b *circuitBreaker) record(ctx context.Context, probe bool, resp *http.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	if probe {
+		b.probing = false
+	}
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	failed := isUpstreamFailure(resp, err)
+	if probe {
+		if failed {
+			b.open(now)
+		} else {
+			b.close()
+		}
+		return
+	}
+	if b.state != circuitClosed {
+		return
+	}
+	if !failed {
+		b.close()
+		return
+	}
+	if b.failures == 0 || now.Sub(b.firstFailure) > b.window {
+		b.failures = 0
+		b.firstFailure = now
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.open(now)
+	}
+}
+
+func (b *circuitBreaker) close() {
+	b.state = circuitClosed
+	b.failures = 0
+}
+
+func (b *circuitBreaker) open(now time.Time) {
+	b.state = circuitOpen
+	b.openedAt = now
+	b.failures = 0
+}
+
+// isUpstreamFailure reports whether a request that ended with resp or err
+// counts against Reddit's health: a transport error or timeout, rate
+// limiting or a server error.
+func isUpstreamFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
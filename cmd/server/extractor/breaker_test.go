@@ -0,0 +1,167 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	var calls atomic.Int64
+	clock := newFakeClock()
+	// Three failures open the circuit, the first probe fails and the second
+	// succeeds.
+	client := statusSequence(&calls,
+		http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusOK)
+	e := mustNewExtractor(WithHTTPClient(client), WithClock(clock), WithCircuitBreaker(3, time.Minute, 30*time.Second))
+	extract := func() error {
+		_, err := e.ExtractSubredditPosts(context.Background(), retryListingURL, "", "", 0, "")
+		return err
+	}
+	wantOpen := func(step string, retryAfter time.Duration) {
+		t.Helper()
+		var open CircuitOpenError
+		if err := extract(); !errors.As(err, &open) {
+			t.Fatalf("%s: err = %v, want CircuitOpenError", step, err)
+		}
+		if open.RetryAfter != retryAfter {
+			t.Errorf("%s: retry after %v, want %v", step, open.RetryAfter, retryAfter)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := extract(); err == nil || errors.As(err, new(CircuitOpenError)) {
+			t.Fatalf("failure %d: err = %v, want the upstream error", i, err)
+		}
+		clock.Advance(time.Second)
+	}
+	wantOpen("open", 29*time.Second)
+	if calls.Load() != 3 {
+		t.Errorf("requests = %d, want 3 with the circuit open", calls.Load())
+	}
+
+	clock.Advance(29 * time.Second)
+	if err := extract(); err == nil || errors.As(err, new(CircuitOpenError)) {
+		t.Fatalf("probe: err = %v, want the upstream error", err)
+	}
+	wantOpen("reopened", 30*time.Second)
+
+	clock.Advance(30 * time.Second)
+	if err := extract(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if err := extract(); err != nil {
+		t.Fatalf("closed: %v", err)
+	}
+	if calls.Load() != 6 {
+		t.Errorf("requests = %d, want 6", calls.Load())
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	clock := newFakeClock()
+	b := &circuitBreaker{threshold: 2, window: time.Minute, cooldown: time.Minute, clock: clock}
+	ctx := context.Background()
+	failed := &http.Response{StatusCode: http.StatusBadGateway}
+
+	b.record(ctx, false, failed, nil)
+	clock.Advance(2 * time.Minute)
+	b.record(ctx, false, failed, nil)
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("failures further apart than the window opened the circuit: %v", err)
+	}
+	b.record(ctx, false, &http.Response{StatusCode: http.StatusOK}, nil)
+	b.record(ctx, false, failed, nil)
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("a success did not reset the failure count: %v", err)
+	}
+	b.record(ctx, false, nil, errors.New("timeout"))
+	if _, err := b.allow(); err == nil {
+		t.Fatal("expected two failures within the window to open the circuit")
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	clock := newFakeClock()
+	b := &circuitBreaker{threshold: 1, window: time.Minute, cooldown: time.Second, clock: clock}
+	b.record(context.Background(), false, nil, errors.New("timeout"))
+	clock.Advance(time.Second)
+
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("probe not allowed: %v", err)
+	}
+	clock.Advance(10 * time.Second)
+	var open CircuitOpenError
+	if _, err := b.allow(); !errors.As(err, &open) {
+		t.Fatalf("err = %v, want a second request to wait for the probe", err)
+	}
+	if open.RetryAfter != time.Second {
+		t.Errorf("retry after %v during a probe running past the cooldown, want the 1s floor", open.RetryAfter)
+	}
+	// A probe abandoned by its caller frees the slot for another one.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.record(ctx, true, nil, context.Canceled)
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("probe not allowed after a canceled one: %v", err)
+	}
+}
+
+func TestCircuitBreakerProbeRetryAfter(t *testing.T) {
+	clock := newFakeClock()
+	b := &circuitBreaker{threshold: 1, window: time.Minute, cooldown: 30 * time.Second, clock: clock}
+	b.record(context.Background(), false, nil, errors.New("timeout"))
+	clock.Advance(30 * time.Second)
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("probe not allowed: %v", err)
+	}
+
+	clock.Advance(5 * time.Second)
+	var open CircuitOpenError
+	if _, err := b.allow(); !errors.As(err, &open) || open.RetryAfter != 25*time.Second {
+		t.Errorf("err = %v, want a CircuitOpenError retrying after the 25s of cooldown left", err)
+	}
+}
+
+func TestWithCircuitBreakerRejectsZeroWindow(t *testing.T) {
+	if _, err := NewExtractor(WithCircuitBreaker(3, 0, time.Minute)); err == nil {
+		t.Error("expected NewExtractor to reject a zero window with several failures")
+	}
+	if _, err := NewExtractor(WithCircuitBreaker(1, 0, time.Minute)); err != nil {
+		t.Errorf("a single failure needs no window: %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleOutcomes(t *testing.T) {
+	clock := newFakeClock()
+	b := &circuitBreaker{threshold: 1, window: time.Minute, cooldown: time.Second, clock: clock}
+	ctx := context.Background()
+
+	// A request allowed while the circuit was closed finishes after another
+	// one opened it and the cooldown passed.
+	stale, err := b.allow()
+	if err != nil || stale {
+		t.Fatalf("allow = %v, %v, want a plain request", stale, err)
+	}
+	b.record(ctx, false, nil, errors.New("timeout"))
+	clock.Advance(time.Second)
+	probe, err := b.allow()
+	if err != nil || !probe {
+		t.Fatalf("allow = %v, %v, want the probe", probe, err)
+	}
+	b.record(ctx, stale, &http.Response{StatusCode: http.StatusOK}, nil)
+
+	var open CircuitOpenError
+	if _, err := b.allow(); !errors.As(err, &open) {
+		t.Fatalf("err = %v, want the probe still in flight", err)
+	}
+	b.record(ctx, probe, &http.Response{StatusCode: http.StatusOK}, nil)
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("a successful probe did not close the circuit: %v", err)
+	}
+}
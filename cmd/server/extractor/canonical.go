@@ -0,0 +1,72 @@
+package extractor
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CanonicalizeRedditURL returns a stable form of a Reddit URL so that
+// equivalent spellings compare equal: the scheme, host, "r" segment and
+// subreddit name are lowercased, empty and trailing path segments are
+// dropped, query parameters are sorted by key and the fragment is removed.
+// Other path segments, such as post slugs, keep their case.
+func CanonicalizeRedditURL(rawURL string) (string, error) {
+	if strings.TrimSpace(rawURL) == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid url")
+	}
+
+	var segments []string
+	for _, seg := range strings.Split(u.Path, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	for i := 0; i+1 < len(segments); i++ {
+		if strings.EqualFold(segments[i], "r") {
+			segments[i] = "r"
+			segments[i+1] = strings.ToLower(segments[i+1])
+			break
+		}
+	}
+
+	canonical := url.URL{
+		Scheme:   strings.ToLower(u.Scheme),
+		Host:     strings.ToLower(u.Host),
+		Path:     "/" + strings.Join(segments, "/"),
+		RawQuery: u.Query().Encode(),
+	}
+	if len(segments) == 0 {
+		canonical.Path = ""
+	}
+	return canonical.String(), nil
+}
+
+// redditAPIBase is the origin of every Reddit API and feed request.
+const redditAPIBase = "https://www.reddit.com"
+
+// redditAPIURL returns the URL of the Reddit endpoint at the path made of
+// segments, in the format ext ("json" or "rss"), with query appended when
+// it is not empty. Every constructed request URL goes through it so they
+// share one form: segments are path-escaped and empty ones dropped, and ext
+// is appended to the last segment, as in /r/golang/hot.json, which Reddit
+// serves directly for every endpoint instead of redirecting.
+func redditAPIURL(ext string, query url.Values, segments ...string) string {
+	var b strings.Builder
+	b.WriteString(redditAPIBase)
+	for _, seg := range segments {
+		if seg = strings.Trim(seg, "/"); seg != "" {
+			b.WriteString("/")
+			b.WriteString(url.PathEscape(seg))
+		}
+	}
+	b.WriteString("." + ext)
+	if len(query) > 0 {
+		b.WriteString("?" + query.Encode())
+	}
+	return b.String()
+}
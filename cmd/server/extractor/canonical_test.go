@@ -0,0 +1,131 @@
+package extractor
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalizeRedditURLVariants(t *testing.T) {
+	variants := []string{
+		"https://www.reddit.com/r/golang",
+		"https://www.reddit.com/r/golang/",
+		"https://www.reddit.com/R/Golang/",
+		"HTTPS://WWW.Reddit.com/r/GOLANG//",
+		"https://www.reddit.com/r/golang/#top",
+	}
+	const want = "https://www.reddit.com/r/golang"
+	for _, v := range variants {
+		got, err := CanonicalizeRedditURL(v)
+		if err != nil {
+			t.Fatalf("CanonicalizeRedditURL(%q) failed: %v", v, err)
+		}
+		if got != want {
+			t.Errorf("CanonicalizeRedditURL(%q) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestCanonicalizeRedditURLQueryOrder(t *testing.T) {
+	a, err := CanonicalizeRedditURL("https://www.reddit.com/r/golang/top/?t=week&limit=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CanonicalizeRedditURL("https://www.reddit.com/r/golang/top?limit=10&t=week")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b || a != "https://www.reddit.com/r/golang/top?limit=10&t=week" {
+		t.Errorf("got %q and %q", a, b)
+	}
+}
+
+func TestCanonicalizeRedditURLKeepsSlugCase(t *testing.T) {
+	got, err := CanonicalizeRedditURL("https://www.reddit.com/r/Golang/comments/abc123/Some_Title/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://www.reddit.com/r/golang/comments/abc123/Some_Title"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCanonicalizeRedditURLInvalid(t *testing.T) {
+	for _, raw := range []string{"", "  ", "not-a-url", "/r/golang"} {
+		if got, err := CanonicalizeRedditURL(raw); err == nil {
+			t.Errorf("CanonicalizeRedditURL(%q) = %q, want error", raw, got)
+		}
+	}
+}
+
+func TestRedditAPIURL(t *testing.T) {
+	cases := []struct {
+		got, want string
+	}{
+		{redditAPIURL("json", nil, "r", "golang", "comments", "abc123"), "https://www.reddit.com/r/golang/comments/abc123.json"},
+		{redditAPIURL("json", nil, "/r/", "golang/", "", "hot"), "https://www.reddit.com/r/golang/hot.json"},
+		{redditAPIURL("rss", url.Values{"limit": {"5"}}, "r", "golang", "new"), "https://www.reddit.com/r/golang/new.rss?limit=5"},
+		{redditAPIURL("json", nil, "user", "a b", "about"), "https://www.reddit.com/user/a%20b/about.json"},
+	}
+	for _, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("got %q, want %q", tc.got, tc.want)
+		}
+	}
+}
+
+// TestAPIURLsIgnoreTrailingSlash checks that post and subreddit URLs given
+// with or without a trailing slash are fetched from the same API URL.
+func TestAPIURLsIgnoreTrailingSlash(t *testing.T) {
+	var requested []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		body := postFixture
+		if !strings.Contains(req.URL.Path, "/comments/") {
+			body = `{"kind": "Listing", "data": {"children": []}}`
+		}
+		return cannedResponse(req, http.StatusOK, body), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	cases := []struct {
+		inputs []string
+		want   string
+		fetch  func(string) error
+	}{
+		{
+			inputs: []string{
+				"https://www.reddit.com/r/golang/comments/abc123/fixture_post/",
+				"https://www.reddit.com/r/golang/comments/abc123/fixture_post",
+				"https://www.reddit.com/r/golang/comments/abc123/",
+				"https://www.reddit.com/r/golang/comments/abc123",
+			},
+			want: "https://www.reddit.com/r/golang/comments/abc123.json",
+			fetch: func(u string) error {
+				_, err := e.ExtractRedditPostWithOptions(context.Background(), u, APIOnly())
+				return err
+			},
+		},
+		{
+			inputs: []string{"https://www.reddit.com/r/golang/", "https://www.reddit.com/r/golang"},
+			want:   "https://www.reddit.com/r/golang/hot.json?limit=20",
+			fetch: func(u string) error {
+				_, err := e.ExtractSubredditPosts(context.Background(), u, "", "", 0, "")
+				return err
+			},
+		},
+	}
+	for _, tc := range cases {
+		for _, input := range tc.inputs {
+			requested = nil
+			if err := tc.fetch(input); err != nil {
+				t.Fatalf("%s: %v", input, err)
+			}
+			if len(requested) != 1 || requested[0] != tc.want {
+				t.Errorf("%s: requested %q, want [%q]", input, requested, tc.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package extractor
+
+import "time"
+
+// Clock tells the current time and waits. Time-dependent extractor logic,
+// such as retry backoffs and budgets, reads the time and waits through a
+// Clock so tests can control it.
+type Clock interface {
+	Now() time.Time
+	// After sends the current time on the returned channel once d has
+	// passed, like time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+func (wallClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,51 @@
+package extractor
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when advanced.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// After advances the clock by d right away, so waits on it do not block.
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Advance(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func TestWithClock(t *testing.T) {
+	if _, ok := mustNewExtractor().clock.(wallClock); !ok {
+		t.Fatal("expected the wall clock by default")
+	}
+
+	clock := newFakeClock()
+	e := mustNewExtractor(WithClock(clock))
+	start := e.clock.Now()
+	clock.Advance(time.Hour)
+	if got := e.clock.Now().Sub(start); got != time.Hour {
+		t.Fatalf("clock advanced by %v, want 1h", got)
+	}
+}
@@ -0,0 +1,102 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/gocolly/colly/v2/extensions"
+)
+
+// newCollector returns a collector for scraping Reddit pages, configured
+// like the crawler in cmd/reddit: a browser user agent picked at random for
+// every request, and one request at a time to Reddit. It may only
+// visit, or be redirected to, the Extractor's allowed hosts, so no handler can
+// make it fetch an arbitrary URL. The collector stops when ctx is done, and
+// sends the user agent set with WithUserAgent instead, if ctx carries one.
+// Its requests go through the Extractor like any API request, see
+// collectorTransport.
+func (e *Extractor) newCollector(ctx context.Context) *colly.Collector {
+	ua := UserAgentFromContext(ctx)
+	opts := []colly.CollectorOption{
+		colly.StdlibContext(ctx),
+		colly.AllowedDomains(e.allowedDomains()...),
+	}
+	if e.acceptLanguage != "" {
+		opts = append(opts, colly.Headers(map[string]string{"Accept-Language": e.acceptLanguage}))
+	}
+	c := colly.NewCollector(opts...)
+	c.SetRequestTimeout(defaultRequestTimeout)
+	c.WithTransport(collectorTransport{e: e})
+	if e.limitRedirects {
+		// Colly checks the allowed domains before calling the handler.
+		c.SetRedirectHandler(e.checkRedirect)
+	}
+	if ua != "" {
+		c.UserAgent = ua
+	} else {
+		extensions.RandomUserAgent(c)
+	}
+	return c
+}
+
+// allowedDomains returns the allowed hosts in a stable order.
+func (e *Extractor) allowedDomains() []string {
+	domains := make([]string, 0, len(e.allowedHosts))
+	for host := range e.allowedHosts {
+		domains = append(domains, host)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// singleHopKey marks a request context whose request must not follow
+// redirects.
+type singleHopKey struct{}
+
+func singleHop(ctx context.Context) bool {
+	v, _ := ctx.Value(singleHopKey{}).(bool)
+	return v
+}
+
+// collectorTransport sends a collector's requests through the Extractor, so
+// HTML scraping shares the concurrency limit, interval gate, Governor,
+// circuit breaker, retries, stats and client of the API requests. Each
+// request is sent for a single hop: the collector follows redirects itself.
+// Requests to Reddit also take the Extractor's HTML slot, which they hold
+// until the response body is closed; the collectors are created per
+// extraction, so a colly limit rule could not span them.
+type collectorTransport struct {
+	e *Extractor
+}
+
+func (t collectorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release := func() {}
+	if isRedditHost(req.URL.Hostname()) {
+		if err := t.e.htmlSem.Acquire(req.Context(), 1); err != nil {
+			return nil, err
+		}
+		release = func() { t.e.htmlSem.Release(1) }
+	}
+	resp, err := t.e.doRequest(req.WithContext(context.WithValue(req.Context(), singleHopKey{}, true)))
+	if err != nil {
+		release()
+		// The collector's own client wraps the error in a *url.Error again.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// isRedditHost reports whether host is reddit.com or one of its subdomains.
+func isRedditHost(host string) bool {
+	return host == "reddit.com" || strings.HasSuffix(host, ".reddit.com")
+}
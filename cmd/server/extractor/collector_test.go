@@ -0,0 +1,156 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+func TestNewCollector(t *testing.T) {
+	var ua atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ua.Store(r.Header.Get("User-Agent"))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	c := mustNewExtractor(WithAllowedHosts([]string{u.Hostname()})).newCollector(context.Background())
+	if err := c.Visit(server.URL); err != nil {
+		t.Fatalf("Visit failed: %v", err)
+	}
+	if got, _ := ua.Load().(string); !strings.HasPrefix(got, "Mozilla/5.0") {
+		t.Errorf("user agent = %q, want a randomized browser user agent", got)
+	}
+}
+
+func TestHTMLFallbackRefusesForeignHosts(t *testing.T) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	_, err := mustNewExtractor().extractRedditPostFromHTML(context.Background(), server.URL+"/r/golang/comments/abc123/x/")
+	if !errors.Is(err, colly.ErrForbiddenDomain) {
+		t.Fatalf("err = %v, want colly.ErrForbiddenDomain", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("server hit %d times, want 0", hits.Load())
+	}
+}
+
+func TestHTMLFallbackRefusesForeignRedirects(t *testing.T) {
+	var foreignHits atomic.Int64
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		foreignHits.Add(1)
+	}))
+	defer foreign.Close()
+	var originHits atomic.Int64
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originHits.Add(1)
+		http.Redirect(w, r, foreign.URL, http.StatusFound)
+	}))
+	defer origin.Close()
+
+	// Both servers listen on 127.0.0.1, so allow them by a name only the
+	// origin is reached through.
+	originURL, _ := url.Parse(origin.URL)
+	e := mustNewExtractor(WithAllowedHosts([]string{"localhost"}))
+	postURL := "http://localhost:" + originURL.Port() + "/r/golang/comments/abc123/x/"
+	if _, err := e.extractRedditPostFromHTML(context.Background(), postURL); err == nil {
+		t.Fatal("expected the redirect to be refused")
+	}
+	if originHits.Load() != 1 || foreignHits.Load() != 0 {
+		t.Errorf("origin hits = %d, foreign hits = %d, want 1 and 0", originHits.Load(), foreignHits.Load())
+	}
+}
+
+func TestHTMLFallbackSendsThroughExtractor(t *testing.T) {
+	var calls atomic.Int64
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		resp := cannedResponse(req, http.StatusOK, `<html><body><h1>Title</h1></body></html>`)
+		resp.Header.Set("Content-Type", "text/html")
+		return resp, nil
+	})}
+	clock := newFakeClock()
+	start := clock.Now()
+	e := mustNewExtractor(WithHTTPClient(client), WithClock(clock), WithMinInterval(time.Minute))
+
+	ctx, stats := WithStats(context.Background())
+	for i := 0; i < 2; i++ {
+		post, err := e.extractRedditPostFromHTML(ctx, testPostURL)
+		if err != nil {
+			t.Fatalf("extractRedditPostFromHTML failed: %v", err)
+		}
+		if post.Title != "Title" {
+			t.Errorf("title = %q, want Title", post.Title)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("client saw %d requests, want 2", calls.Load())
+	}
+	if stats.Requests() != 2 {
+		t.Errorf("stats counted %d requests, want 2", stats.Requests())
+	}
+	if got := clock.Now().Sub(start); got != time.Minute {
+		t.Errorf("clock advanced %v, want the minimum interval", got)
+	}
+}
+
+func TestHTMLFallbackOneRequestAtATime(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int64
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		resp := cannedResponse(req, http.StatusOK, `<html><body><h1>Title</h1></body></html>`)
+		resp.Header.Set("Content-Type", "text/html")
+		return resp, nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	var wg sync.WaitGroup
+	for _, postURL := range []string{testPostURL, testPostURL, "https://reddit.com/r/golang/comments/abc123/fixture_post/", "https://old.reddit.com/r/golang/comments/abc123/fixture_post/"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := e.extractRedditPostFromHTML(context.Background(), postURL); err != nil {
+				t.Errorf("extractRedditPostFromHTML(%s) failed: %v", postURL, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := maxInFlight.Load(); got != 1 {
+		t.Errorf("%d requests were in flight at once, want 1", got)
+	}
+}
+
+func TestIsRedditHost(t *testing.T) {
+	for host, want := range map[string]bool{
+		"reddit.com":     true,
+		"www.reddit.com": true,
+		"old.reddit.com": true,
+		"notreddit.com":  false,
+		"i.redd.it":      false,
+	} {
+		if got := isRedditHost(host); got != want {
+			t.Errorf("isRedditHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
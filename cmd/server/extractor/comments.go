@@ -0,0 +1,253 @@
+package extractor
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	mdImageRE      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkRE       = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdSpoilerRE    = regexp.MustCompile(`>!(.*?)!<`)
+	mdCodeRE       = regexp.MustCompile("`([^`]*)`")
+	mdStrongRE     = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	mdEmRE         = regexp.MustCompile(`(^|[^\w*])[*_]([^*_\n]+)[*_]`)
+	mdStrikeRE     = regexp.MustCompile(`~~(.+?)~~`)
+	mdLinePrefixRE = regexp.MustCompile(`(?m)^[ \t]*(?:(?:>[ \t]?)+|#{1,6}[ \t]+|[*+-][ \t]+)`)
+)
+
+// commentBodyOptions is the subset of extractOptions applied to comment
+// bodies.
+type commentBodyOptions struct {
+	unescape  bool
+	plainText bool
+	maxRunes  int
+}
+
+func (c commentBodyOptions) isZero() bool {
+	return c == commentBodyOptions{}
+}
+
+// commentAuthorFilter is the subset of extractOptions selecting comments by
+// author. Names are stored lower-cased, as Reddit matches them without case.
+type commentAuthorFilter struct {
+	deny        map[string]bool
+	allow       map[string]bool
+	keepOrphans bool
+}
+
+func (f commentAuthorFilter) isZero() bool {
+	return f.deny == nil && f.allow == nil
+}
+
+// keeps reports whether a comment by author passes the filter.
+func (f commentAuthorFilter) keeps(author string) bool {
+	author = strings.ToLower(author)
+	if f.deny[author] {
+		return false
+	}
+	return f.allow == nil || f.allow[author]
+}
+
+// apply filters the comment tree at every depth. A dropped comment takes
+// its replies with it unless keepOrphans is set, in which case the replies
+// that pass the filter take its place.
+func (f commentAuthorFilter) apply(comments []Comment) []Comment {
+	if len(comments) == 0 {
+		return comments
+	}
+	kept := make([]Comment, 0, len(comments))
+	for _, c := range comments {
+		keep := f.keeps(c.Author)
+		if !keep && !f.keepOrphans {
+			continue
+		}
+		c.Replies = f.apply(c.Replies)
+		if keep {
+			kept = append(kept, c)
+		} else {
+			kept = append(kept, c.Replies...)
+		}
+	}
+	return kept
+}
+
+// addAuthors adds names to the lower-cased set, creating it if needed.
+func addAuthors(set map[string]bool, names []string) map[string]bool {
+	if set == nil {
+		set = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		set[strings.ToLower(name)] = true
+	}
+	return set
+}
+
+// htmlComment is a comment scraped from a shreddit-comment element, with
+// the nesting level given by its depth attribute.
+type htmlComment struct {
+	depth   int
+	comment Comment
+}
+
+// nestHTMLComments builds the comment tree from comments in document order:
+// each comment becomes a reply of the closest preceding one with a lower
+// depth. A comment whose depth is missing or skips levels is therefore still
+// attached to its nearest ancestor.
+func nestHTMLComments(flat []htmlComment) []Comment {
+	comments, _ := nestHTMLCommentsFrom(flat, 0, 0)
+	return comments
+}
+
+// nestHTMLCommentsFrom returns the comments of flat, starting at i, that are
+// at least minDepth deep, nesting their replies, and the index of the first
+// comment after them.
+func nestHTMLCommentsFrom(flat []htmlComment, i, minDepth int) ([]Comment, int) {
+	var comments []Comment
+	for i < len(flat) && flat[i].depth >= minDepth {
+		c := flat[i].comment
+		c.Replies, i = nestHTMLCommentsFrom(flat, i+1, flat[i].depth+1)
+		comments = append(comments, c)
+	}
+	return comments, i
+}
+
+// automoderatorAuthor is the account name of Reddit's AutoModerator bot.
+const automoderatorAuthor = "AutoModerator"
+
+// dropTopLevelComments removes the top-level comments for which drop
+// reports true, together with their replies.
+func dropTopLevelComments(comments []Comment, drop func(Comment) bool) []Comment {
+	kept := comments[:0]
+	for _, c := range comments {
+		if !drop(c) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// FlatComment is a Comment without its replies, placed in a flat list by
+// FlattenComments.
+type FlatComment struct {
+	ID            string `json:"id,omitempty"`
+	Permalink     string `json:"permalink,omitempty"`
+	Author        string `json:"author,omitempty"`
+	Body          string `json:"body"`
+	Score         int    `json:"score"`
+	Distinguished string `json:"distinguished,omitempty"`
+	Stickied      bool   `json:"stickied,omitempty"`
+	CreatedUTC    int64  `json:"created_utc,omitempty"`
+	IsSubmitter   bool   `json:"is_submitter,omitempty"`
+	Edited        bool   `json:"edited,omitempty"`
+	EditedAt      string `json:"edited_at,omitempty"`
+	// Depth is 0 for top-level comments, and ParentIndex the index of the
+	// parent comment in the flat list, or -1 for top-level comments.
+	Depth       int `json:"depth"`
+	ParentIndex int `json:"parent_index"`
+}
+
+// FlattenComments lists the comments of a tree in pre-order, so every
+// comment follows its parent and precedes its own replies.
+func FlattenComments(comments []Comment) []FlatComment {
+	// pending comments are pushed in reverse so they pop in order.
+	type pending struct {
+		comment *Comment
+		depth   int
+		parent  int
+	}
+	var flat []FlatComment
+	var stack []pending
+	push := func(comments []Comment, depth, parent int) {
+		for i := len(comments) - 1; i >= 0; i-- {
+			stack = append(stack, pending{comment: &comments[i], depth: depth, parent: parent})
+		}
+	}
+	push(comments, 0, -1)
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		c := p.comment
+		flat = append(flat, FlatComment{
+			ID:            c.ID,
+			Permalink:     c.Permalink,
+			Author:        c.Author,
+			Body:          c.Body,
+			Score:         c.Score,
+			Distinguished: c.Distinguished,
+			Stickied:      c.Stickied,
+			CreatedUTC:    c.CreatedUTC,
+			IsSubmitter:   c.IsSubmitter,
+			Edited:        c.Edited,
+			EditedAt:      c.EditedAt,
+			Depth:         p.depth,
+			ParentIndex:   p.parent,
+		})
+		push(c.Replies, p.depth+1, len(flat)-1)
+	}
+	return flat
+}
+
+// cleanCommentBodies applies c to every body in the comment tree, in place.
+func cleanCommentBodies(comments []Comment, c commentBodyOptions) {
+	for i := range comments {
+		comments[i].Body = cleanCommentBody(comments[i].Body, c)
+		cleanCommentBodies(comments[i].Replies, c)
+	}
+}
+
+// cleanCommentBody unescapes, strips and truncates body as c asks, in that
+// order. Plain text implies unescaping, since quote markers arrive as &gt;.
+func cleanCommentBody(body string, c commentBodyOptions) string {
+	if c.unescape || c.plainText {
+		body = html.UnescapeString(body)
+	}
+	if c.plainText {
+		body = stripMarkdown(body)
+	}
+	if c.maxRunes > 0 {
+		body = truncateRunes(body, c.maxRunes, true)
+	}
+	return body
+}
+
+// stripMarkdown reduces Reddit markdown to plain text: link and image
+// syntax keeps only its text, and emphasis, code, spoiler, quote, heading
+// and list markers are removed. It is a best-effort cleanup for text
+// analysis, not a full markdown parser.
+func stripMarkdown(s string) string {
+	s = mdImageRE.ReplaceAllString(s, "$1")
+	s = mdLinkRE.ReplaceAllString(s, "$1")
+	s = mdSpoilerRE.ReplaceAllString(s, "$1")
+	s = mdLinePrefixRE.ReplaceAllString(s, "")
+	s = mdCodeRE.ReplaceAllString(s, "$1")
+	s = mdStrongRE.ReplaceAllString(s, "$2")
+	s = mdStrikeRE.ReplaceAllString(s, "$1")
+	s = mdEmRE.ReplaceAllString(s, "$1$2")
+	return strings.TrimSpace(s)
+}
+
+// topComments returns the n highest-scored comments, keeping each one's
+// direct replies only if withReplies is set. Reddit's "top" sort already
+// orders them, but sorting again keeps the result right for any input.
+func topComments(comments []Comment, n int, withReplies bool) []Comment {
+	top := append([]Comment(nil), comments...)
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Score > top[j].Score })
+	if len(top) > n {
+		top = top[:n]
+	}
+	for i := range top {
+		if !withReplies {
+			top[i].Replies = nil
+			continue
+		}
+		replies := append([]Comment(nil), top[i].Replies...)
+		for j := range replies {
+			replies[j].Replies = nil
+		}
+		top[i].Replies = replies
+	}
+	return top
+}
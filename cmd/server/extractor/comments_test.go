@@ -0,0 +1,677 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// decodeChildren unmarshals a JSON array of listing children.
+func decodeChildren(t *testing.T, body string) []json.RawMessage {
+	t.Helper()
+	var children []json.RawMessage
+	if err := json.Unmarshal([]byte(body), &children); err != nil {
+		t.Fatalf("unmarshal children fixture: %v", err)
+	}
+	return children
+}
+
+func TestParseCommentListingsDistinguished(t *testing.T) {
+	comments, _ := parseCommentListings(decodeChildren(t, `[
+		{"kind": "t1", "data": {"body": "Please follow the rules.", "distinguished": "moderator", "replies": {
+			"kind": "Listing", "data": {"children": [
+				{"kind": "t1", "data": {"body": "thanks", "distinguished": null, "is_submitter": true, "replies": ""}}
+			]}
+		}}},
+		{"kind": "t1", "data": {"body": "plain", "replies": ""}}
+	]`))
+
+	if len(comments) != 2 {
+		t.Fatalf("got %d comments, want 2", len(comments))
+	}
+	if comments[0].Distinguished != "moderator" || comments[0].IsSubmitter {
+		t.Errorf("unexpected first comment: %+v", comments[0])
+	}
+	reply := comments[0].Replies[0]
+	if reply.Distinguished != "" || !reply.IsSubmitter {
+		t.Errorf("unexpected reply: %+v", reply)
+	}
+	if comments[1].Distinguished != "" || comments[1].IsSubmitter {
+		t.Errorf("unexpected second comment: %+v", comments[1])
+	}
+}
+
+func TestParseCommentListingsEdited(t *testing.T) {
+	comments, _ := parseCommentListings(decodeChildren(t, `[
+		{"kind": "t1", "data": {"body": "original", "edited": false}},
+		{"kind": "t1", "data": {"body": "fixed typo", "edited": 1700000000.0}}
+	]`))
+
+	if comments[0].Edited || comments[0].EditedAt != "" {
+		t.Errorf("unexpected unedited comment: %+v", comments[0])
+	}
+	if want := time.Unix(1700000000, 0).Format(timeLayout); !comments[1].Edited || comments[1].EditedAt != want {
+		t.Errorf("edited comment = %+v, want edited_at %q", comments[1], want)
+	}
+}
+
+func TestCleanCommentBodyUnescape(t *testing.T) {
+	got := cleanCommentBody("&gt; quoted &amp; &lt;tag&gt;", commentBodyOptions{unescape: true})
+	if want := "> quoted & <tag>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCleanCommentBodyPlainText(t *testing.T) {
+	body := "&gt; someone said\n\n**Bold** and *em* with [a link](https://go.dev) and `code`\n\n* item\n\n~~gone~~ &gt;!spoiler!&lt;"
+	got := cleanCommentBody(body, commentBodyOptions{plainText: true})
+	want := "someone said\n\nBold and em with a link and code\n\nitem\n\ngone spoiler"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCleanCommentBodyTruncatesAtRuneBoundary(t *testing.T) {
+	cases := []struct {
+		body string
+		max  int
+		want string
+	}{
+		{body: "short", max: 10, want: "short"},
+		{body: "exactly10!", max: 10, want: "exactly10!"},
+		{body: "héllo wörld", max: 6, want: "héllo…"},
+		{body: "日本語のテキスト", max: 4, want: "日本語…"},
+		{body: "go 🐹🐹🐹", max: 5, want: "go 🐹…"},
+	}
+	for _, tc := range cases {
+		got := cleanCommentBody(tc.body, commentBodyOptions{maxRunes: tc.max})
+		if got != tc.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tc.body, tc.max, got, tc.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) produced invalid UTF-8", tc.body, tc.max)
+		}
+	}
+}
+
+func TestExtractRedditPostCommentCleanup(t *testing.T) {
+	body := `[
+		{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Fixture post"}}]}},
+		{"kind": "Listing", "data": {"children": [
+			{"kind": "t1", "data": {"body": "&gt; a very long quoted comment", "replies": {
+				"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"body": "**reply** here", "replies": ""}}]}
+			}}}
+		]}}
+	]`
+	e := fixtureExtractor(body)
+
+	post, err := e.ExtractRedditPost(context.Background(), testPostURL)
+	if err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	if post.Comments[0].Body != "&gt; a very long quoted comment" {
+		t.Errorf("default extraction changed the body: %q", post.Comments[0].Body)
+	}
+
+	post, err = e.ExtractRedditPostWithOptions(context.Background(), testPostURL, PlainTextComments(), TruncateComments(10))
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if got := post.Comments[0].Body; got != "a very lo…" {
+		t.Errorf("top-level body = %q", got)
+	}
+	if got := post.Comments[0].Replies[0].Body; got != "reply here" {
+		t.Errorf("reply body = %q", got)
+	}
+}
+
+func TestTopComments(t *testing.T) {
+	body := `[
+		{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Fixture post"}}]}},
+		{"kind": "Listing", "data": {"children": [
+			{"kind": "t1", "data": {"body": "low", "score": 3, "replies": ""}},
+			{"kind": "t1", "data": {"body": "high", "score": 50, "replies": {
+				"kind": "Listing", "data": {"children": [
+					{"kind": "t1", "data": {"body": "reply", "score": 1, "replies": {
+						"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"body": "deep", "replies": ""}}]}
+					}}}
+				]}
+			}}},
+			{"kind": "t1", "data": {"body": "mid", "score": 20, "replies": ""}}
+		]}}
+	]`
+	var query string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		query = req.URL.RawQuery
+		return cannedResponse(req, http.StatusOK, body), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	post, err := e.ExtractRedditPostWithOptions(context.Background(), testPostURL, TopComments(2, true))
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if query != "sort=top" {
+		t.Errorf("query = %q, want sort=top", query)
+	}
+	if len(post.Comments) != 2 || post.Comments[0].Body != "high" || post.Comments[1].Body != "mid" {
+		t.Fatalf("unexpected comments: %+v", post.Comments)
+	}
+	for i := 1; i < len(post.Comments); i++ {
+		if post.Comments[i-1].Score < post.Comments[i].Score {
+			t.Errorf("comments not in descending score order: %+v", post.Comments)
+		}
+	}
+	if replies := post.Comments[0].Replies; len(replies) != 1 || replies[0].Replies != nil {
+		t.Errorf("expected one direct reply without nested replies, got %+v", replies)
+	}
+
+	post, err = e.ExtractRedditPostWithOptions(context.Background(), testPostURL, TopComments(1, false))
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if len(post.Comments) != 1 || post.Comments[0].Replies != nil {
+		t.Errorf("unexpected comments: %+v", post.Comments)
+	}
+}
+
+func TestSuggestedSort(t *testing.T) {
+	body := func(comment string) string {
+		return `[
+			{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Fixture post", "suggested_sort": "new"}}]}},
+			{"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"body": "` + comment + `", "replies": ""}}]}}
+		]`
+	}
+	var queries []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		queries = append(queries, req.URL.RawQuery)
+		if req.URL.Query().Get("sort") == "new" {
+			return cannedResponse(req, http.StatusOK, body("newest")), nil
+		}
+		return cannedResponse(req, http.StatusOK, body("default")), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	post, err := e.ExtractRedditPost(context.Background(), testPostURL)
+	if err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	if post.SuggestedSort != "new" {
+		t.Errorf("suggested sort = %q, want new", post.SuggestedSort)
+	}
+	if len(post.Comments) != 1 || post.Comments[0].Body != "newest" {
+		t.Errorf("comments = %+v, want them in the suggested order", post.Comments)
+	}
+	if len(queries) != 2 || queries[1] != "sort=new" {
+		t.Errorf("queries = %q, want a refetch with sort=new", queries)
+	}
+
+	// An explicit sort wins over the suggestion.
+	queries = nil
+	if _, err := e.ExtractRedditPostWithOptions(context.Background(), testPostURL, TopComments(1, false)); err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if len(queries) != 1 || queries[0] != "sort=top" {
+		t.Errorf("queries = %q, want only sort=top", queries)
+	}
+}
+
+func TestSuggestedSortRefetchFails(t *testing.T) {
+	body := `[
+		{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Fixture post", "suggested_sort": "new"}}]}},
+		{"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"body": "default", "replies": ""}}]}}
+	]`
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("sort") == "new" {
+			return cannedResponse(req, http.StatusNotFound, `{}`), nil
+		}
+		return cannedResponse(req, http.StatusOK, body), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	post, err := e.ExtractRedditPostWithOptions(context.Background(), testPostURL, APIOnly())
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if post.Title != "Fixture post" || len(post.Comments) != 1 || post.Comments[0].Body != "default" {
+		t.Errorf("post = %+v, want the first response with its comments unsorted", post)
+	}
+}
+
+func TestIncludeCommentsFalse(t *testing.T) {
+	post, err := fixtureExtractor(postFixture).ExtractRedditPostWithOptions(context.Background(), testPostURL, IncludeComments(false))
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if post.Title != "Fixture post" || len(post.Comments) != 0 {
+		t.Errorf("title = %q, comments = %+v, want the post without comments", post.Title, post.Comments)
+	}
+}
+
+// largeThreadFixture returns a post with n top-level comments, each with a
+// reply.
+func largeThreadFixture(n int) string {
+	var b strings.Builder
+	b.WriteString(`[{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Big thread"}}]}},
+		{"kind": "Listing", "data": {"children": [`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"kind": "t1", "data": {"body": "comment %d with some text in it", "score": %d, "replies": {
+			"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"body": "a reply", "score": 1, "replies": ""}}]}}}}`, i, i)
+	}
+	b.WriteString(`]}}]`)
+	return b.String()
+}
+
+func BenchmarkExtractLargeThread(b *testing.B) {
+	e := fixtureExtractor(largeThreadFixture(2000))
+	for _, include := range []bool{true, false} {
+		b.Run(fmt.Sprintf("comments=%v", include), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := e.extractRedditPostFromAPI(context.Background(), testPostURL, applyExtractOptions([]ExtractOption{IncludeComments(include)})); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// commentTreeChildren returns the children of a comment listing with the
+// given breadth at every level, nested depth levels deep.
+func commentTreeChildren(t testing.TB, breadth, depth int) []json.RawMessage {
+	t.Helper()
+	var build func(level int) string
+	build = func(level int) string {
+		var b strings.Builder
+		b.WriteString("[")
+		for i := 0; i < breadth; i++ {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			replies := `""`
+			if level < depth {
+				replies = `{"kind": "Listing", "data": {"children": ` + build(level+1) + `}}`
+			}
+			fmt.Fprintf(&b, `{"kind": "t1", "data": {"body": "comment at level %d", "score": %d, "replies": %s}}`, level, i, replies)
+		}
+		b.WriteString("]")
+		return b.String()
+	}
+	var children []json.RawMessage
+	if err := json.Unmarshal([]byte(build(1)), &children); err != nil {
+		t.Fatal(err)
+	}
+	return children
+}
+
+func BenchmarkParseCommentListings(b *testing.B) {
+	children := commentTreeChildren(b, 4, 6)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parseCommentListings(children)
+	}
+}
+
+func TestParseCommentListingsNestedOrder(t *testing.T) {
+	comments, _ := parseCommentListings(commentTreeChildren(t, 2, 3))
+	var count func([]Comment, int) int
+	count = func(cs []Comment, level int) int {
+		n := 0
+		for i, c := range cs {
+			if want := fmt.Sprintf("comment at level %d", level); c.Body != want || c.Score != i {
+				t.Errorf("level %d comment %d = %q (score %d)", level, i, c.Body, c.Score)
+			}
+			n += 1 + count(c.Replies, level+1)
+		}
+		return n
+	}
+	if n := count(comments, 1); n != 2+4+8 {
+		t.Errorf("parsed %d comments, want 14", n)
+	}
+}
+
+func TestParseCommentListingsDepthCap(t *testing.T) {
+	// A single reply chain nested well past the cap.
+	const depth = maxCommentDepth + 100
+	var b strings.Builder
+	for i := 0; i < depth; i++ {
+		b.WriteString(`{"kind": "t1", "data": {"body": "c", "replies": {"kind": "Listing", "data": {"children": [`)
+	}
+	for i := 0; i < depth; i++ {
+		b.WriteString(`]}}}}`)
+	}
+	var children []json.RawMessage
+	if err := json.Unmarshal([]byte("["+b.String()+"]"), &children); err != nil {
+		t.Fatalf("building fixture: %v", err)
+	}
+
+	comments, truncated := parseCommentListings(children)
+	if !truncated {
+		t.Error("expected the tree to be reported as truncated")
+	}
+	levels := 0
+	for cs := comments; len(cs) > 0; cs = cs[0].Replies {
+		levels++
+	}
+	if levels != maxCommentDepth {
+		t.Errorf("kept %d levels, want %d", levels, maxCommentDepth)
+	}
+
+	if _, truncated := parseCommentListings(commentTreeChildren(t, 2, 3)); truncated {
+		t.Error("a shallow tree should not be truncated")
+	}
+}
+
+const htmlCommentsFixture = `<html><body>
+<shreddit-post><h1>HTML post</h1></shreddit-post>
+<shreddit-comment-tree>
+  <shreddit-comment author="alice" depth="0" score="12" thingid="t1_a" permalink="/r/golang/comments/abc123/comment/a/">
+    <div slot="comment"><p>Top level.</p><p>Second paragraph.</p></div>
+    <shreddit-comment author="bob" depth="1" score="5" thingid="t1_b">
+      <div slot="comment"><p>A reply.</p></div>
+      <shreddit-comment author="carol" depth="2" score="-1" thingid="t1_c">
+        <div slot="comment"><p>A nested reply.</p></div>
+      </shreddit-comment>
+    </shreddit-comment>
+    <shreddit-comment author="dave" depth="1" score="2" thingid="t1_d">
+      <div slot="comment"><p>Another reply.</p></div>
+    </shreddit-comment>
+  </shreddit-comment>
+  <shreddit-comment author="erin" depth="0" score="3" thingid="t1_e">
+    <div slot="comment">No paragraph markup.</div>
+  </shreddit-comment>
+</shreddit-comment-tree>
+</body></html>`
+
+func TestExtractRedditPostFromHTMLComments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, htmlCommentsFixture)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	e := mustNewExtractor(WithAllowedHosts([]string{u.Hostname()}))
+	postURL := server.URL + "/r/golang/comments/abc123/html_post/"
+
+	post, err := e.ExtractRedditPostWithOptions(context.Background(), postURL, HTMLOnly())
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	want := []Comment{
+		{ID: "a", Permalink: "https://www.reddit.com/r/golang/comments/abc123/comment/a/", Author: "alice", Body: "Top level.\n\nSecond paragraph.", Score: 12, Replies: []Comment{
+			{ID: "b", Author: "bob", Body: "A reply.", Score: 5, Replies: []Comment{{ID: "c", Author: "carol", Body: "A nested reply.", Score: -1}}},
+			{ID: "d", Author: "dave", Body: "Another reply.", Score: 2},
+		}},
+		{ID: "e", Author: "erin", Body: "No paragraph markup.", Score: 3},
+	}
+	if !reflect.DeepEqual(post.Comments, want) {
+		t.Errorf("comments = %+v, want %+v", post.Comments, want)
+	}
+
+	post, err = e.ExtractRedditPostWithOptions(context.Background(), postURL, HTMLOnly(), TopComments(1, false))
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if len(post.Comments) != 1 || post.Comments[0].Score != 12 || post.Comments[0].Replies != nil {
+		t.Errorf("top comments = %+v, want only the top-level comment scored 12", post.Comments)
+	}
+}
+
+func TestNestHTMLComments(t *testing.T) {
+	// A reply whose depth skips a level still nests under the comment before
+	// it, and a later shallower comment closes the open levels.
+	got := nestHTMLComments([]htmlComment{
+		{depth: 0, comment: Comment{Body: "a"}},
+		{depth: 2, comment: Comment{Body: "b"}},
+		{depth: 1, comment: Comment{Body: "c"}},
+		{depth: 0, comment: Comment{Body: "d"}},
+	})
+	want := []Comment{
+		{Body: "a", Replies: []Comment{{Body: "b"}, {Body: "c"}}},
+		{Body: "d"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nestHTMLComments = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseCommentListingsIDAndPermalink(t *testing.T) {
+	comments, _ := parseCommentListings(decodeChildren(t, `[
+		{"kind": "t1", "data": {"id": "c1", "name": "t1_c1", "body": "top",
+			"permalink": "/r/golang/comments/abc123/fixture_post/c1/",
+			"replies": {"kind": "Listing", "data": {"children": [
+				{"kind": "t1", "data": {"name": "t1_c2", "body": "reply", "permalink": "/r/golang/comments/abc123/fixture_post/c2/", "replies": ""}}
+			]}}}},
+		{"kind": "t1", "data": {"body": "no metadata", "replies": ""}}
+	]`))
+	if len(comments) != 2 || len(comments[0].Replies) != 1 {
+		t.Fatalf("unexpected tree: %+v", comments)
+	}
+	top, reply := comments[0], comments[0].Replies[0]
+	if top.ID != "c1" || top.Permalink != "https://www.reddit.com/r/golang/comments/abc123/fixture_post/c1/" {
+		t.Errorf("top: id %q, permalink %q", top.ID, top.Permalink)
+	}
+	if reply.ID != "c2" || reply.Permalink != "https://www.reddit.com/r/golang/comments/abc123/fixture_post/c2/" {
+		t.Errorf("reply: id %q from its name, permalink %q", reply.ID, reply.Permalink)
+	}
+	if comments[1].ID != "" || comments[1].Permalink != "" {
+		t.Errorf("comment without metadata: id %q, permalink %q, want both empty", comments[1].ID, comments[1].Permalink)
+	}
+}
+
+func TestFlattenComments(t *testing.T) {
+	tree := []Comment{
+		{ID: "a", Replies: []Comment{
+			{ID: "b", Replies: []Comment{{ID: "c"}}},
+			{ID: "d"},
+		}},
+		{ID: "e", Score: 7, Replies: []Comment{{ID: "f"}}},
+	}
+	type link struct {
+		id            string
+		depth, parent int
+	}
+	want := []link{{"a", 0, -1}, {"b", 1, 0}, {"c", 2, 1}, {"d", 1, 0}, {"e", 0, -1}, {"f", 1, 4}}
+
+	flat := FlattenComments(tree)
+	if len(flat) != len(want) {
+		t.Fatalf("got %d comments, want %d: %+v", len(flat), len(want), flat)
+	}
+	for i, w := range want {
+		if got := (link{flat[i].ID, flat[i].Depth, flat[i].ParentIndex}); got != w {
+			t.Errorf("comment %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if flat[4].Score != 7 {
+		t.Errorf("score = %d, want the comment fields copied", flat[4].Score)
+	}
+	if FlattenComments(nil) != nil {
+		t.Error("want nil for no comments")
+	}
+}
+
+func TestDropStickiedAndAutoModeratorComments(t *testing.T) {
+	body := `[
+		{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Fixture post"}}]}},
+		{"kind": "Listing", "data": {"children": [
+			{"kind": "t1", "data": {"author": "AutoModerator", "body": "Please read the rules.", "stickied": true, "distinguished": "moderator", "replies": {
+				"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"author": "gopher", "body": "ok", "replies": ""}}]}
+			}}},
+			{"kind": "t1", "data": {"author": "mod", "body": "Pinned note", "stickied": true, "replies": ""}},
+			{"kind": "t1", "data": {"author": "AutoModerator", "body": "Unpinned bot reply", "replies": ""}},
+			{"kind": "t1", "data": {"author": "gopher", "body": "plain", "replies": ""}}
+		]}}
+	]`
+	e := fixtureExtractor(body)
+
+	cases := []struct {
+		name string
+		opts []ExtractOption
+		want []string
+	}{
+		{"default", nil, []string{"Please read the rules.", "Pinned note", "Unpinned bot reply", "plain"}},
+		{"stickied", []ExtractOption{DropStickiedComments()}, []string{"Unpinned bot reply", "plain"}},
+		{"automoderator", []ExtractOption{DropAutoModeratorComments()}, []string{"Pinned note", "plain"}},
+		{"both", []ExtractOption{DropStickiedComments(), DropAutoModeratorComments()}, []string{"plain"}},
+	}
+	for _, tc := range cases {
+		post, err := e.ExtractRedditPostWithOptions(context.Background(), testPostURL, tc.opts...)
+		if err != nil {
+			t.Fatalf("%s: ExtractRedditPostWithOptions failed: %v", tc.name, err)
+		}
+		var got []string
+		for _, c := range post.Comments {
+			got = append(got, c.Body)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: comments = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// commentAuthors lists the authors of a comment tree in pre-order, with
+// their depth.
+func commentAuthors(comments []Comment) []string {
+	var authors []string
+	for _, c := range FlattenComments(comments) {
+		authors = append(authors, fmt.Sprintf("%d:%s", c.Depth, c.Author))
+	}
+	return authors
+}
+
+func TestCommentAuthorFilters(t *testing.T) {
+	body := `[
+		{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Fixture post"}}]}},
+		{"kind": "Listing", "data": {"children": [
+			{"kind": "t1", "data": {"author": "alice", "body": "a", "replies": {
+				"kind": "Listing", "data": {"children": [
+					{"kind": "t1", "data": {"author": "RemindMeBot", "body": "b", "replies": {
+						"kind": "Listing", "data": {"children": [
+							{"kind": "t1", "data": {"author": "bob", "body": "c", "replies": {
+								"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"author": "alice", "body": "d", "replies": ""}}]}
+							}}}
+						]}
+					}}},
+					{"kind": "t1", "data": {"author": "carol", "body": "e", "replies": ""}}
+				]}
+			}}},
+			{"kind": "t1", "data": {"author": "remindmebot", "body": "f", "replies": ""}}
+		]}}
+	]`
+	e := fixtureExtractor(body)
+
+	cases := []struct {
+		name string
+		opts []ExtractOption
+		want []string
+	}{
+		{"default", nil, []string{"0:alice", "1:RemindMeBot", "2:bob", "3:alice", "1:carol", "0:remindmebot"}},
+		{"deny drops subtree", []ExtractOption{ExcludeCommentAuthors("remindmebot")}, []string{"0:alice", "1:carol"}},
+		{"deny keeps orphans", []ExtractOption{ExcludeCommentAuthors("RemindMeBot"), KeepOrphanedReplies()}, []string{"0:alice", "1:bob", "2:alice", "1:carol"}},
+		{"allow", []ExtractOption{OnlyCommentAuthors("alice", "carol")}, []string{"0:alice", "1:carol"}},
+		{"allow keeps orphans", []ExtractOption{OnlyCommentAuthors("alice"), KeepOrphanedReplies()}, []string{"0:alice", "1:alice"}},
+		{"deny wins over allow", []ExtractOption{OnlyCommentAuthors("alice", "carol"), ExcludeCommentAuthors("carol")}, []string{"0:alice"}},
+	}
+	for _, tc := range cases {
+		post, err := e.ExtractRedditPostWithOptions(context.Background(), testPostURL, tc.opts...)
+		if err != nil {
+			t.Fatalf("%s: ExtractRedditPostWithOptions failed: %v", tc.name, err)
+		}
+		if got := commentAuthors(post.Comments); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: comments = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestContestMode(t *testing.T) {
+	body := `[
+		{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"id": "abc123", "title": "Fixture post", "contest_mode": true}}]}},
+		{"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"body": "entry", "score": 1, "replies": ""}}]}}
+	]`
+	post, err := fixtureExtractor(body).ExtractRedditPostWithOptions(context.Background(), testPostURL, TopComments(1, false))
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if !post.ContestMode {
+		t.Fatal("expected contest mode to be parsed")
+	}
+
+	var buf bytes.Buffer
+	logger := log.New(&buf, "", 0)
+	warnContestMode(logger, post, "")
+	if buf.Len() != 0 {
+		t.Errorf("warned without a requested sort: %q", buf.String())
+	}
+	warnContestMode(logger, post, "top")
+	if want := "contest mode: post=abc123, sort=top, comment order and scores may be unreliable\n"; buf.String() != want {
+		t.Errorf("warning = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	post.ContestMode = false
+	warnContestMode(logger, post, "top")
+	if buf.Len() != 0 {
+		t.Errorf("warned for a post not in contest mode: %q", buf.String())
+	}
+}
+
+func TestExtractRedditComments(t *testing.T) {
+	body := `[
+		{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Fixture post", "suggested_sort": "new"}}]}},
+		{"kind": "Listing", "data": {"children": [
+			{"kind": "t1", "data": {"id": "a", "author": "alice", "body": "low", "score": 1, "replies": {
+				"kind": "Listing", "data": {"children": [{"kind": "t1", "data": {"id": "b", "body": "reply", "replies": ""}}]}
+			}}},
+			{"kind": "t1", "data": {"id": "c", "author": "carol", "body": "high", "score": 9, "replies": ""}}
+		]}}
+	]`
+	var requests []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.URL.String())
+		return cannedResponse(req, http.StatusOK, body), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	comments, err := e.ExtractRedditComments(context.Background(), testPostURL)
+	if err != nil {
+		t.Fatalf("ExtractRedditComments failed: %v", err)
+	}
+	if got := commentAuthors(comments); !reflect.DeepEqual(got, []string{"0:alice", "1:", "0:carol"}) {
+		t.Errorf("comments = %q", got)
+	}
+	if want := []string{"https://www.reddit.com/r/golang/comments/abc123.json"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %q, want %q without following the suggested sort", requests, want)
+	}
+
+	comments, err = e.ExtractRedditComments(context.Background(), testPostURL, TopComments(1, false))
+	if err != nil {
+		t.Fatalf("ExtractRedditComments failed: %v", err)
+	}
+	if len(comments) != 1 || comments[0].ID != "c" {
+		t.Errorf("top comments = %+v, want only c", comments)
+	}
+
+	comments, err = fixtureExtractor(`[{"kind": "Listing", "data": {"children": []}}, {"kind": "Listing", "data": {"children": []}}]`).ExtractRedditComments(context.Background(), testPostURL)
+	if err != nil || comments == nil || len(comments) != 0 {
+		t.Errorf("empty thread: comments = %#v, err = %v, want an empty slice", comments, err)
+	}
+
+	if _, err := e.ExtractRedditComments(context.Background(), "https://www.reddit.com/r/golang/"); !errors.Is(err, errNotAPost) {
+		t.Errorf("err = %v, want errNotAPost", err)
+	}
+}
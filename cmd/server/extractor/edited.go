@@ -0,0 +1,45 @@
+package extractor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// editedField decodes Reddit's polymorphic edited field, which is false for
+// unedited items and the edit's Unix timestamp (a float) otherwise. Some
+// endpoints send true without a timestamp, and null is treated as unedited.
+type editedField struct {
+	Edited bool
+	At     time.Time
+}
+
+func (f *editedField) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch string(data) {
+	case "null", "false":
+		*f = editedField{}
+		return nil
+	case "true":
+		*f = editedField{Edited: true}
+		return nil
+	}
+	var ts float64
+	if err := json.Unmarshal(data, &ts); err != nil {
+		return fmt.Errorf("edited: expected bool or timestamp, got %s", data)
+	}
+	*f = editedField{Edited: ts > 0}
+	if ts > 0 {
+		f.At = time.Unix(int64(ts), 0)
+	}
+	return nil
+}
+
+// formattedAt returns the edit time in timeLayout, or "" if unknown.
+func (f editedField) formattedAt() string {
+	if f.At.IsZero() {
+		return ""
+	}
+	return f.At.Format(timeLayout)
+}
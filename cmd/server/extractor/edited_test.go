@@ -0,0 +1,55 @@
+package extractor
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEditedFieldUnmarshal(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		wantEdited bool
+		wantAt     time.Time
+		wantErr    bool
+	}{
+		{name: "false", json: `false`},
+		{name: "null", json: `null`},
+		{name: "timestamp", json: `1700000000.0`, wantEdited: true, wantAt: time.Unix(1700000000, 0)},
+		{name: "integer timestamp", json: `1700000000`, wantEdited: true, wantAt: time.Unix(1700000000, 0)},
+		{name: "true", json: `true`, wantEdited: true},
+		{name: "string", json: `"yes"`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var f editedField
+			err := json.Unmarshal([]byte(tc.json), &f)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			if f.Edited != tc.wantEdited || !f.At.Equal(tc.wantAt) {
+				t.Errorf("got %+v, want edited=%v at=%v", f, tc.wantEdited, tc.wantAt)
+			}
+		})
+	}
+}
+
+func TestEditedFieldInStruct(t *testing.T) {
+	var v struct {
+		Edited editedField `json:"edited"`
+	}
+	if err := json.Unmarshal([]byte(`{}`), &v); err != nil || v.Edited.Edited {
+		t.Fatalf("absent field: got %+v, %v", v.Edited, err)
+	}
+	if v.Edited.formattedAt() != "" {
+		t.Errorf("formattedAt = %q, want empty", v.Edited.formattedAt())
+	}
+}
@@ -0,0 +1,124 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// blockedSnippetLen bounds how much of a block page BlockedError keeps.
+const blockedSnippetLen = 200
+
+// BlockedError reports that Reddit answered an API request with an HTML page
+// (typically an anti-bot or Cloudflare challenge) instead of JSON. Retrying
+// with a different user agent or proxy usually helps.
+type BlockedError struct {
+	StatusCode int
+	Snippet    string
+}
+
+func (e BlockedError) Error() string {
+	return fmt.Sprintf("request appears blocked by reddit (status %d, non-json response): %s", e.StatusCode, e.Snippet)
+}
+
+// AgeGatedError reports that Reddit answered with its over-18 interstitial
+// instead of the post. Extracting with ViewNSFW confirms the prompt.
+type AgeGatedError struct {
+	URL string
+}
+
+func (e AgeGatedError) Error() string {
+	return fmt.Sprintf("post is age-gated (nsfw), retry with ViewNSFW: %s", e.URL)
+}
+
+// isAgeGate reports whether resp is Reddit's over-18 interstitial, either
+// because the request was redirected to /over18 or because an HTML body
+// carries its confirmation form.
+func isAgeGate(resp *http.Response, body []byte) bool {
+	if resp.Request != nil && strings.HasPrefix(resp.Request.URL.Path, "/over18") {
+		return true
+	}
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] == '<' && bytes.Contains(trimmed, []byte("over18"))
+}
+
+// checkJSONResponse returns a BlockedError if resp declares an HTML content
+// type or its body does not look like JSON.
+func checkJSONResponse(resp *http.Response, body []byte) error {
+	trimmed := bytes.TrimSpace(body)
+	isHTML := len(trimmed) > 0 && trimmed[0] == '<'
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		isHTML = isHTML || mediaType == "text/html"
+	}
+	if !isHTML {
+		return nil
+	}
+	return BlockedError{StatusCode: resp.StatusCode, Snippet: bodySnippet(trimmed)}
+}
+
+// UpstreamError reports a JSON error object Reddit answered with, such as
+// {"message": "Not Found", "error": 404}, instead of the expected listing.
+type UpstreamError struct {
+	// Code is the error field of the object, usually an HTTP status.
+	Code    int
+	Message string
+	// Reason, when Reddit gives one, is a short tag such as "private" or
+	// "banned".
+	Reason string
+}
+
+func (e UpstreamError) Error() string {
+	msg := fmt.Sprintf("reddit returned error %d: %s", e.Code, e.Message)
+	if e.Reason != "" {
+		msg += " (" + e.Reason + ")"
+	}
+	return msg
+}
+
+// parseUpstreamError returns the UpstreamError held by body when it is a
+// JSON error object rather than an array or a listing object, and nil
+// otherwise.
+func parseUpstreamError(body []byte) error {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil
+	}
+	var obj struct {
+		Kind    string `json:"kind"`
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal(trimmed, &obj); err != nil || obj.Kind != "" || obj.Error == 0 {
+		return nil
+	}
+	return UpstreamError{Code: obj.Error, Message: obj.Message, Reason: obj.Reason}
+}
+
+// unexpectedStatus returns the error for a response with an unexpected
+// status: the UpstreamError in its body if it has one, or a plain status
+// error otherwise.
+func unexpectedStatus(ctx context.Context, resp *http.Response) error {
+	if body, err := readBody(ctx, resp.Body); err == nil {
+		if upstreamErr := parseUpstreamError(body); upstreamErr != nil {
+			return upstreamErr
+		}
+	}
+	return fmt.Errorf("unexpected status: %s", resp.Status)
+}
+
+// bodySnippet returns the start of body as a single line of valid UTF-8.
+func bodySnippet(body []byte) string {
+	if len(body) > blockedSnippetLen {
+		body = body[:blockedSnippetLen]
+		for len(body) > 0 && !utf8.Valid(body) {
+			body = body[:len(body)-1]
+		}
+	}
+	return strings.Join(strings.Fields(string(body)), " ")
+}
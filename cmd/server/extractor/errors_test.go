@@ -0,0 +1,166 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+const blockPage = `<!DOCTYPE html>
+<html><head><title>Blocked</title></head>
+<body>You've been blocked by network security.</body></html>`
+
+func htmlExtractor(body string) *Extractor {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp := cannedResponse(req, http.StatusOK, body)
+		resp.Header.Set("Content-Type", "text/html; charset=utf-8")
+		return resp, nil
+	})}
+	return mustNewExtractor(WithHTTPClient(client))
+}
+
+func TestBlockedErrorFromPostAPI(t *testing.T) {
+	_, err := htmlExtractor(blockPage).extractRedditPostFromAPI(context.Background(), testPostURL, extractOptions{})
+	var blocked BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("err = %v, want BlockedError", err)
+	}
+	if blocked.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", blocked.StatusCode)
+	}
+	if want := "<!DOCTYPE html> <html><head><title>Blocked</title></head> <body>You've been blocked by network security.</body></html>"; blocked.Snippet != want {
+		t.Errorf("snippet = %q, want %q", blocked.Snippet, want)
+	}
+}
+
+func TestBlockedErrorFromListing(t *testing.T) {
+	_, err := htmlExtractor(blockPage).ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+	var blocked BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("err = %v, want BlockedError", err)
+	}
+}
+
+func TestCheckJSONResponseLeadingAngleBracket(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": []string{"application/json"}}}
+	if err := checkJSONResponse(resp, []byte("  <html>")); err == nil {
+		t.Error("expected a BlockedError for an html body with a json content type")
+	}
+	if err := checkJSONResponse(resp, []byte(`{"kind": "Listing"}`)); err != nil {
+		t.Errorf("unexpected error for json body: %v", err)
+	}
+}
+
+func TestBodySnippetTruncatesAtRuneBoundary(t *testing.T) {
+	body := make([]byte, 0, blockedSnippetLen+3)
+	for len(body) < blockedSnippetLen-1 {
+		body = append(body, 'a')
+	}
+	body = append(body, "é!"...)
+	snippet := bodySnippet(body)
+	if len(snippet) != blockedSnippetLen-1 {
+		t.Errorf("snippet length = %d, want %d", len(snippet), blockedSnippetLen-1)
+	}
+}
+
+const ageGatePage = `<!DOCTYPE html>
+<html><body>
+<h1>You must be 18+ to view this community</h1>
+<form action="/over18" method="POST"><input type="hidden" name="over18" value="yes"></form>
+</body></html>`
+
+// ageGateExtractor serves the over-18 interstitial unless the request
+// carries the over18 cookie, in which case it serves postFixture.
+func ageGateExtractor(calls *atomic.Int64) *Extractor {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		if c, err := req.Cookie("over18"); err == nil && c.Value == "1" {
+			return cannedResponse(req, http.StatusOK, postFixture), nil
+		}
+		resp := cannedResponse(req, http.StatusOK, ageGatePage)
+		resp.Header.Set("Content-Type", "text/html; charset=utf-8")
+		return resp, nil
+	})}
+	return mustNewExtractor(WithHTTPClient(client))
+}
+
+func TestAgeGatedPost(t *testing.T) {
+	var calls atomic.Int64
+	_, err := ageGateExtractor(&calls).ExtractRedditPost(context.Background(), testPostURL)
+	var gated AgeGatedError
+	if !errors.As(err, &gated) {
+		t.Fatalf("err = %v, want AgeGatedError", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("requests = %d, want 1 with no HTML fallback", calls.Load())
+	}
+}
+
+func TestViewNSFWConfirmsAgeGate(t *testing.T) {
+	var calls atomic.Int64
+	post, err := ageGateExtractor(&calls).ExtractRedditPostWithOptions(context.Background(), testPostURL, ViewNSFW())
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if post.Title != "Fixture post" {
+		t.Errorf("title = %q, want the fixture title", post.Title)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("requests = %d, want 2", calls.Load())
+	}
+}
+
+func TestIsAgeGateRedirect(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://www.reddit.com/over18?dest=https%3A%2F%2Fwww.reddit.com%2Fr%2Fgolang%2F", nil)
+	if !isAgeGate(&http.Response{Request: req}, []byte("{}")) {
+		t.Error("expected a redirect to /over18 to be detected")
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, testPostURL, nil)
+	if isAgeGate(&http.Response{Request: req}, []byte(blockPage)) {
+		t.Error("a plain block page is not an age gate")
+	}
+}
+
+func TestUpstreamErrorObject(t *testing.T) {
+	const body = `{"message": "Forbidden", "error": 403, "reason": "private"}`
+	want := UpstreamError{Code: 403, Message: "Forbidden", Reason: "private"}
+	statusExtractor := func(status int) *Extractor {
+		client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return cannedResponse(req, status, body), nil
+		})}
+		return mustNewExtractor(WithHTTPClient(client))
+	}
+
+	for _, status := range []int{http.StatusOK, http.StatusInternalServerError} {
+		e := statusExtractor(status)
+		_, err := e.ExtractRedditPostWithOptions(context.Background(), testPostURL, APIOnly())
+		var upstream UpstreamError
+		if !errors.As(err, &upstream) || upstream != want {
+			t.Errorf("post, status %d: err = %v, want %v", status, err, want)
+		}
+		_, err = e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+		if !errors.As(err, &upstream) || upstream != want {
+			t.Errorf("listing, status %d: err = %v, want %v", status, err, want)
+		}
+	}
+}
+
+func TestParseUpstreamError(t *testing.T) {
+	for _, body := range []string{
+		`[{"kind": "Listing"}]`,
+		`{"kind": "Listing", "data": {"children": []}}`,
+		`{"error": "invalid_grant"}`,
+		`{}`,
+		``,
+	} {
+		if err := parseUpstreamError([]byte(body)); err != nil {
+			t.Errorf("parseUpstreamError(%q) = %v, want nil", body, err)
+		}
+	}
+	if err := parseUpstreamError([]byte(` {"message": "Not Found", "error": 404}`)); err == nil || err.Error() != "reddit returned error 404: Not Found" {
+		t.Errorf("err = %v, want the 404 error object", err)
+	}
+}
@@ -3,75 +3,311 @@ package extractor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly/v2"
 )
 
 const (
-	apiUserAgent  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"
-	htmlUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+	apiUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"
+
+	defaultRequestTimeout = 12 * time.Second
+
+	// timeLayout formats the timestamps of extracted posts.
+	timeLayout = "2006-01-02 15:04:05"
 )
 
 var (
-	redditURLRE = regexp.MustCompile(`/r/([^/]+)/comments/([a-z0-9]+)/`)
-	scoreLikeRE = regexp.MustCompile(`^\d+\.?[\d]*[kK]?$`)
+	// subredditNameRE matches a subreddit name, or several joined with "+"
+	// as in multireddit URLs. Anything else, such as "..", is rejected so a
+	// parsed name can be spliced into an API URL path as is.
+	subredditNameRE = regexp.MustCompile(`^[A-Za-z0-9_]+(\+[A-Za-z0-9_]+)*$`)
+	postIDRE        = regexp.MustCompile(`^[a-z0-9]+$`)
+	scoreLikeRE     = regexp.MustCompile(`^\d+\.?[\d]*[kK]?$`)
 )
 
+// defaultAllowedHosts are the Reddit hosts accepted unless WithAllowedHosts
+// says otherwise.
+var defaultAllowedHosts = []string{
+	"reddit.com",
+	"www.reddit.com",
+	"old.reddit.com",
+	"new.reddit.com",
+	"m.reddit.com",
+}
+
+// errNotAPost is returned for URLs that do not point at a Reddit post.
+var errNotAPost = ValidationError{Message: "invalid reddit post url"}
+
+// defaultExtractor backs the package-level extraction functions.
+var defaultExtractor = mustNewExtractor()
+
+// Extractor extracts Reddit posts and subreddit listings. Create one with
+// NewExtractor; the package-level functions use a default Extractor.
+type Extractor struct {
+	httpClient *http.Client
+	// hopClient is httpClient without redirects, for the HTML collector,
+	// which follows them itself.
+	hopClient *http.Client
+	transport *http.Transport
+	tracer    Tracer
+	clock     Clock
+	sem       *semaphore.Weighted
+	// htmlSem keeps HTML scraping to one request at a time to Reddit.
+	htmlSem *semaphore.Weighted
+
+	minInterval time.Duration
+	gate        *intervalGate
+	governor    *Governor
+
+	allowedHosts map[string]struct{}
+
+	// maxRedirects applies when limitRedirects is set.
+	maxRedirects   int
+	limitRedirects bool
+
+	postFilter func(SubredditPost) bool
+
+	acceptLanguage string
+
+	emptyListingRetries int
+	emptyListingDelay   time.Duration
+
+	defaultSort      string
+	defaultLimit     int
+	maxLimit         int
+	strictValidation bool
+	fallbackToRSS    bool
+
+	retries         int
+	retryBudget     time.Duration
+	retryBaseDelay  time.Duration
+	retryMultiplier float64
+	retryJitter     JitterMode
+	// random returns values in [0, 1) for the retry jitter.
+	random func() float64
+
+	breakerThreshold int
+	breakerWindow    time.Duration
+	breakerCooldown  time.Duration
+	breaker          *circuitBreaker
+
+	// postFlight coalesces concurrent fetches of the same post.
+	postFlight singleflight.Group
+}
+
+// NewExtractor returns an Extractor configured with the given options. It
+// fails if an option was given an invalid value that cannot be fixed up,
+// such as an unknown default sort.
+func NewExtractor(opts ...Option) (*Extractor, error) {
+	e := &Extractor{
+		defaultSort:     defaultSubredditSort,
+		defaultLimit:    defaultSubredditLimit,
+		maxLimit:        maxSubredditLimit,
+		retryBaseDelay:  defaultRetryBaseDelay,
+		retryMultiplier: defaultRetryMultiplier,
+	}
+	WithAllowedHosts(defaultAllowedHosts)(e)
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.httpClient == nil {
+		transport := e.transport
+		if transport == nil {
+			transport = defaultTransport
+		}
+		e.httpClient = &http.Client{Timeout: defaultRequestTimeout, Transport: transport}
+	}
+	if e.limitRedirects {
+		// Copy a client given with WithHTTPClient rather than change it
+		// under its owner.
+		client := *e.httpClient
+		client.CheckRedirect = e.checkRedirect
+		e.httpClient = &client
+	}
+	// The collector checks every redirect target against the allowed hosts,
+	// so its requests must come back at the first response. It keeps its
+	// own cookies, too.
+	hop := *e.httpClient
+	hop.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	hop.Jar = nil
+	e.hopClient = &hop
+	e.htmlSem = semaphore.NewWeighted(1)
+	if e.tracer == nil {
+		e.tracer = noopTracer{}
+	}
+	if e.clock == nil {
+		e.clock = wallClock{}
+	}
+	if e.random == nil {
+		e.random = rand.Float64
+	}
+	if e.defaultLimit > e.maxLimit {
+		e.defaultLimit = e.maxLimit
+	}
+	if e.minInterval > 0 {
+		e.gate = &intervalGate{interval: e.minInterval}
+	}
+	if e.breakerThreshold > 1 && e.breakerWindow <= 0 {
+		return nil, fmt.Errorf("circuit breaker window must be positive, got %v", e.breakerWindow)
+	}
+	if e.breakerThreshold > 0 {
+		e.breaker = &circuitBreaker{
+			threshold: e.breakerThreshold,
+			window:    e.breakerWindow,
+			cooldown:  e.breakerCooldown,
+			clock:     e.clock,
+		}
+	}
+	sort := normalizeSubredditSort(e.defaultSort)
+	if sort == "" {
+		return nil, fmt.Errorf("invalid default sort: %q", e.defaultSort)
+	}
+	e.defaultSort = sort
+	return e, nil
+}
+
+// mustNewExtractor is NewExtractor for options known to be valid.
+func mustNewExtractor(opts ...Option) *Extractor {
+	e, err := NewExtractor(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
 // Comment represents a Reddit comment with nested replies.
 type Comment struct {
-	Body    string    `json:"body"`
-	Replies []Comment `json:"replies,omitempty"`
+	// ID is the comment's base-36 ID, without the t1_ prefix, and Permalink
+	// the full URL of the comment. CreatedUTC is when it was posted, in Unix
+	// seconds.
+	ID            string    `json:"id,omitempty"`
+	Permalink     string    `json:"permalink,omitempty"`
+	Author        string    `json:"author,omitempty"`
+	Body          string    `json:"body"`
+	Score         int       `json:"score"`
+	Distinguished string    `json:"distinguished,omitempty"`
+	Stickied      bool      `json:"stickied,omitempty"`
+	CreatedUTC    int64     `json:"created_utc,omitempty"`
+	IsSubmitter   bool      `json:"is_submitter,omitempty"`
+	Edited        bool      `json:"edited,omitempty"`
+	EditedAt      string    `json:"edited_at,omitempty"`
+	Replies       []Comment `json:"replies,omitempty"`
 }
 
+// Values of RedditPost.Source.
+const (
+	SourceAPI    = "api"
+	SourceHTML   = "html"
+	SourceMerged = "merged" // API result backfilled from HTML
+)
+
 // RedditPost represents extracted information from a Reddit post.
 type RedditPost struct {
-	Title         string   `json:"title"`
-	Author        string   `json:"author"`
-	PublishedTime string   `json:"published_time"`
-	Score         string   `json:"score"`
-	CommentCount  string   `json:"comment_count"`
-	Content       string   `json:"content"`
-	Images        []string `json:"images"`
-	Comments      []Comment `json:"comments"`
+	ID            string    `json:"id,omitempty"`
+	Title         string    `json:"title"`
+	Author        string    `json:"author"`
+	PublishedTime string    `json:"published_time"`
+	Score         string    `json:"score"`
+	CommentCount  string    `json:"comment_count"`
+	Content       string    `json:"content"`
+	Images        []string  `json:"images"`
+	Embed         *Embed    `json:"embed,omitempty"`
+	Poll          *Poll     `json:"poll,omitempty"`
+	Gildings      *Gildings `json:"gildings,omitempty"`
+	Distinguished string    `json:"distinguished,omitempty"`
+	Stickied      bool      `json:"stickied,omitempty"`
+	Edited        bool      `json:"edited,omitempty"`
+	EditedAt      string    `json:"edited_at,omitempty"`
+	Locked        bool      `json:"locked,omitempty"`
+	Archived      bool      `json:"archived,omitempty"`
+	Crossposts    int       `json:"crossposts,omitempty"`
+	ViewCount     int       `json:"view_count,omitempty"`
+	SuggestedSort string    `json:"suggested_sort,omitempty"`
+	// ContestMode is set for posts whose comments Reddit shuffles and shows
+	// without scores, so neither their order nor their scores mean much.
+	ContestMode bool      `json:"contest_mode,omitempty"`
+	Comments    []Comment `json:"comments"`
+	// CommentsTruncated is set when replies nested deeper than the internal
+	// safety cap were dropped.
+	CommentsTruncated bool `json:"comments_truncated,omitempty"`
+	// Source tells which extraction path produced the post: SourceAPI,
+	// SourceHTML or SourceMerged. Fields the HTML page does not show, such
+	// as SuggestedSort, are only filled from the API.
+	Source string `json:"source,omitempty"`
+	// ScoreInt and CommentCountInt are Score and CommentCount as numbers,
+	// matching SubredditPost. They are only filled from the API and stay
+	// zero for SourceHTML posts, whose counts are display text such as
+	// "1.2k". The string fields are kept for existing clients and will be
+	// deprecated in favour of these.
+	ScoreInt        int `json:"score_int"`
+	CommentCountInt int `json:"comment_count_int"`
 }
 
 // RedditAPIResponse represents the structure of Reddit's JSON API response.
-type RedditAPIResponse []struct {
+type RedditAPIResponse []RedditAPIListing
+
+// RedditAPIListing is one element of a RedditAPIResponse. The first holds
+// the post; the second holds its comments, which are parsed separately.
+type RedditAPIListing struct {
 	Kind string `json:"kind"`
 	Data struct {
 		Children []struct {
 			Kind string `json:"kind"`
 			Data struct {
-				Title         string  `json:"title"`
-				Author        string  `json:"author"`
-				CreatedUTC    float64 `json:"created_utc"`
-				Score         int     `json:"score"`
-				NumComments   int     `json:"num_comments"`
-				Selftext      string  `json:"selftext"`
-				IsGallery     bool    `json:"is_gallery"`
-				URL           string  `json:"url"`
-				MediaMetadata map[string]struct {
-					Status string `json:"status"`
-					E      string `json:"e"`
-					M      string `json:"m"`
-					S      struct {
-						U string `json:"u"`
-					} `json:"s"`
-				} `json:"media_metadata"`
+				ID            string                     `json:"id"`
+				Name          string                     `json:"name"`
+				Title         string                     `json:"title"`
+				Author        string                     `json:"author"`
+				CreatedUTC    float64                    `json:"created_utc"`
+				Score         int                        `json:"score"`
+				NumComments   int                        `json:"num_comments"`
+				Selftext      string                     `json:"selftext"`
+				IsGallery     bool                       `json:"is_gallery"`
+				URL           string                     `json:"url"`
+				Media         *redditMedia               `json:"media"`
+				SecureMedia   *redditMedia               `json:"secure_media"`
+				PollData      *redditPollData            `json:"poll_data"`
+				Gildings      redditGildings             `json:"gildings"`
+				Distinguished string                     `json:"distinguished"`
+				Stickied      bool                       `json:"stickied"`
+				Edited        editedField                `json:"edited"`
+				Locked        bool                       `json:"locked"`
+				Archived      bool                       `json:"archived"`
+				NumCrossposts int                        `json:"num_crossposts"`
+				ViewCount     int                        `json:"view_count"` // usually null
+				SuggestedSort string                     `json:"suggested_sort"`
+				ContestMode   bool                       `json:"contest_mode"`
+				GalleryData   *redditGalleryData         `json:"gallery_data"`
+				MediaMetadata map[string]redditMediaItem `json:"media_metadata"`
 			} `json:"data"`
 		} `json:"children"`
 	} `json:"data"`
 }
 
-// ValidateRedditURL returns an error if the URL is empty or malformed.
+// ValidateRedditURL validates rawURL against the default Extractor's
+// allowed hosts.
 func ValidateRedditURL(rawURL string) error {
+	return defaultExtractor.ValidateRedditURL(rawURL)
+}
+
+// ValidateRedditURL returns an error if the URL is empty, malformed, or not on
+// one of the Extractor's allowed Reddit hosts.
+func (e *Extractor) ValidateRedditURL(rawURL string) error {
 	if strings.TrimSpace(rawURL) == "" {
 		return fmt.Errorf("url is required")
 	}
@@ -82,31 +318,180 @@ func ValidateRedditURL(rawURL string) error {
 	if parsed.Scheme == "" || parsed.Host == "" {
 		return fmt.Errorf("invalid url")
 	}
+	return e.checkHost(parsed)
+}
+
+// checkHost returns an error unless u's host is one of the allowed hosts.
+func (e *Extractor) checkHost(u *url.URL) error {
+	if _, ok := e.allowedHosts[strings.ToLower(u.Hostname())]; !ok {
+		return fmt.Errorf("host not allowed: %s", u.Hostname())
+	}
 	return nil
 }
 
-// ExtractRedditPost extracts post data from Reddit by trying JSON API first,
-// falling back to HTML scraping if needed.
+// ExtractRedditPost extracts post data from Reddit using the default Extractor.
 func ExtractRedditPost(ctx context.Context, redditURL string) (*RedditPost, error) {
-	if err := ValidateRedditURL(redditURL); err != nil {
+	return defaultExtractor.ExtractRedditPost(ctx, redditURL)
+}
+
+// ExtractRedditPost extracts post data from Reddit by trying JSON API first,
+// falling back to HTML scraping if needed. Comments follow the post's
+// suggested sort when it has one, at the cost of a second request.
+func (e *Extractor) ExtractRedditPost(ctx context.Context, redditURL string) (*RedditPost, error) {
+	return e.ExtractRedditPostWithOptions(ctx, redditURL)
+}
+
+// ExtractRedditPostWithOptions extracts post data using the default Extractor.
+func ExtractRedditPostWithOptions(ctx context.Context, redditURL string, opts ...ExtractOption) (*RedditPost, error) {
+	return defaultExtractor.ExtractRedditPostWithOptions(ctx, redditURL, opts...)
+}
+
+// ExtractRedditPostWithOptions is ExtractRedditPost with per-call options;
+// without any it behaves exactly like ExtractRedditPost.
+func (e *Extractor) ExtractRedditPostWithOptions(ctx context.Context, redditURL string, opts ...ExtractOption) (post *RedditPost, err error) {
+	o := applyExtractOptions(opts)
+	ctx, span := e.tracer.Start(ctx, "extractor.ExtractRedditPost")
+	span.SetAttribute("reddit.url", redditURL)
+	defer func() {
+		if post != nil {
+			span.SetAttribute("reddit.comment_count", len(post.Comments))
+		}
+		endSpan(span, err)
+	}()
+
+	if err := e.ValidateRedditURL(redditURL); err != nil {
 		return nil, err
 	}
-	post, err := extractRedditPostFromAPI(ctx, redditURL)
-	if err != nil || post == nil || post.Title == "" {
-		post, err = extractRedditPostFromHTML(ctx, redditURL)
-		if err != nil {
+	if _, _, ok := parseRedditURL(redditURL); !ok {
+		return nil, errNotAPost
+	}
+	if o.source != sourceHTML {
+		post, err = e.extractRedditPostFromAPI(ctx, redditURL, o)
+		if err == nil && post != nil && post.Title != "" {
+			post.Source = SourceAPI
+			if o.preferHTMLWhenIncomplete && isIncompletePost(post) {
+				if htmlPost, htmlErr := e.extractRedditPostFromHTML(ctx, redditURL); htmlErr == nil {
+					post = mergeRedditPosts(post, htmlPost)
+				}
+			}
+			post.Images = o.finishImages(post.Images)
+			post.Comments = o.finishComments(post.Comments)
+			warnContestMode(newLogger(ctx, "post"), post, o.commentSort)
+			return post, nil
+		}
+		var gated AgeGatedError
+		var open CircuitOpenError
+		if o.source == sourceAPI || errors.As(err, &gated) || errors.As(err, &open) {
+			if err == nil {
+				err = fmt.Errorf("no post found in api response")
+			}
 			return nil, err
 		}
 	}
+	post, err = e.extractRedditPostFromHTML(ctx, redditURL)
+	if err != nil {
+		return nil, err
+	}
+	post.Source = SourceHTML
+	post.Images = o.finishImages(post.Images)
+	if o.skipComments {
+		post.Comments = nil
+	}
+	post.Comments = o.finishComments(post.Comments)
 	return post, nil
 }
 
+// isIncompletePost reports whether post lacks a field the HTML page can
+// supply.
+func isIncompletePost(post *RedditPost) bool {
+	return post.Author == "" || post.PublishedTime == "" || post.Content == ""
+}
+
+// mergeRedditPosts fills the empty fields of primary from secondary and
+// returns primary, so the primary result stays authoritative while the
+// secondary one backfills its gaps. Images of secondary not already present
+// are appended, and comments are taken only if primary has none. Boolean
+// flags are left as primary has them, and Source becomes SourceMerged. A
+// nil primary yields secondary.
+func mergeRedditPosts(primary, secondary *RedditPost) *RedditPost {
+	if primary == nil {
+		return secondary
+	}
+	if secondary == nil {
+		return primary
+	}
+	setIfEmpty(&primary.ID, secondary.ID)
+	setIfEmpty(&primary.Title, secondary.Title)
+	setIfEmpty(&primary.Author, secondary.Author)
+	setIfEmpty(&primary.PublishedTime, secondary.PublishedTime)
+	setIfEmpty(&primary.Score, secondary.Score)
+	setIfEmpty(&primary.CommentCount, secondary.CommentCount)
+	setIfEmpty(&primary.Content, secondary.Content)
+	setIfEmpty(&primary.Distinguished, secondary.Distinguished)
+	setIfEmpty(&primary.EditedAt, secondary.EditedAt)
+
+	have := make(map[string]bool, len(primary.Images))
+	for _, img := range primary.Images {
+		have[img] = true
+	}
+	for _, img := range secondary.Images {
+		if !have[img] {
+			have[img] = true
+			primary.Images = append(primary.Images, img)
+		}
+	}
+
+	if primary.Embed == nil {
+		primary.Embed = secondary.Embed
+	}
+	if primary.Poll == nil {
+		primary.Poll = secondary.Poll
+	}
+	if primary.Gildings == nil {
+		primary.Gildings = secondary.Gildings
+	}
+	if len(primary.Comments) == 0 && len(secondary.Comments) > 0 {
+		primary.Comments = secondary.Comments
+	}
+	primary.Source = SourceMerged
+	return primary
+}
+
+// parseRedditURL extracts the subreddit and post ID from a
+// /r/{subreddit}/comments/{id} URL path.
 func parseRedditURL(redditURL string) (string, string, bool) {
-	matches := redditURLRE.FindStringSubmatch(redditURL)
-	if len(matches) < 3 {
+	u, err := url.Parse(redditURL)
+	if err != nil {
 		return "", "", false
 	}
-	return matches[1], matches[2], true
+	parts := strings.Split(u.Path, "/")
+	for i := 0; i+3 < len(parts); i++ {
+		if parts[i] != "r" || parts[i+2] != "comments" {
+			continue
+		}
+		subreddit, postID := parts[i+1], parts[i+3]
+		if subredditNameRE.MatchString(subreddit) && postIDRE.MatchString(postID) {
+			return subreddit, postID, true
+		}
+	}
+	return "", "", false
+}
+
+// canonicalPostID returns the base-36 post ID, taken from the id field or,
+// failing that, from the "t3_"-prefixed fullname.
+func canonicalPostID(id, name string) string {
+	if id = strings.TrimSpace(id); id != "" {
+		return id
+	}
+	return strings.TrimPrefix(strings.TrimSpace(name), "t3_")
+}
+
+// commentID is canonicalPostID for comments, whose fullnames start with t1_.
+func commentID(id, name string) string {
+	if id = strings.TrimSpace(id); id != "" {
+		return id
+	}
+	return strings.TrimPrefix(strings.TrimSpace(name), "t1_")
 }
 
 func isRedditImageURL(url string) bool {
@@ -120,142 +505,391 @@ func setIfEmpty(target *string, value string) {
 	}
 }
 
-func extractRedditPostFromAPI(ctx context.Context, redditURL string) (*RedditPost, error) {
+func (e *Extractor) extractRedditPostFromAPI(ctx context.Context, redditURL string, o extractOptions) (*RedditPost, error) {
 	subreddit, postID, ok := parseRedditURL(redditURL)
 	if !ok {
-		return nil, fmt.Errorf("invalid reddit post url")
-	}
-
-	jsonURL := fmt.Sprintf("https://www.reddit.com/r/%s/comments/%s/.json", subreddit, postID)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", jsonURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("User-Agent", apiUserAgent)
-
-	client := &http.Client{
-		Timeout: 12 * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, errNotAPost
 	}
 
-	// Read the entire response body first to enable multiple parsing passes
-	bodyBytes, err := io.ReadAll(resp.Body)
+	elements, err := e.fetchPostElements(ctx, subreddit, postID, o)
 	if err != nil {
 		return nil, err
 	}
 
-	var apiResponse RedditAPIResponse
-	if err := json.Unmarshal(bodyBytes, &apiResponse); err != nil {
-		return nil, err
-	}
-
 	post := &RedditPost{}
 
-	// First pass: Extract post data from the first element (t3 post)
-	for _, item := range apiResponse {
-		if item.Kind != "Listing" {
-			continue
+	// Extract post data from the first element (t3 post)
+	if len(elements) > 0 {
+		var item RedditAPIListing
+		if err := json.Unmarshal(elements[0], &item); err != nil {
+			return nil, err
 		}
 		for _, child := range item.Data.Children {
-			if child.Kind != "t3" {
+			if item.Kind != "Listing" || child.Kind != "t3" {
 				continue
 			}
+			post.ID = canonicalPostID(child.Data.ID, child.Data.Name)
 			post.Title = child.Data.Title
 			post.Author = child.Data.Author
 			post.Score = fmt.Sprintf("%d", child.Data.Score)
 			post.CommentCount = fmt.Sprintf("%d", child.Data.NumComments)
+			post.ScoreInt = child.Data.Score
+			post.CommentCountInt = child.Data.NumComments
 			post.Content = child.Data.Selftext
+			post.Embed = buildEmbed(child.Data.SecureMedia, child.Data.Media)
+			post.Poll = buildPoll(child.Data.PollData)
+			post.Gildings = buildGildings(child.Data.Gildings)
+			post.Distinguished = child.Data.Distinguished
+			post.Stickied = child.Data.Stickied
+			post.Edited, post.EditedAt = child.Data.Edited.Edited, child.Data.Edited.formattedAt()
+			post.Locked = child.Data.Locked
+			post.Archived = child.Data.Archived
+			post.Crossposts = child.Data.NumCrossposts
+			post.ViewCount = child.Data.ViewCount
+			post.SuggestedSort = child.Data.SuggestedSort
+			post.ContestMode = child.Data.ContestMode
 
 			if child.Data.CreatedUTC > 0 {
-				post.PublishedTime = time.Unix(int64(child.Data.CreatedUTC), 0).Format("2006-01-02 15:04:05")
+				post.PublishedTime = time.Unix(int64(child.Data.CreatedUTC), 0).Format(timeLayout)
 			}
 
 			if child.Data.IsGallery && child.Data.MediaMetadata != nil {
-				for _, media := range child.Data.MediaMetadata {
-					if media.Status == "valid" && media.E == "Image" && media.S.U != "" {
-						imageURL := strings.ReplaceAll(media.S.U, "&amp;", "&")
-						post.Images = append(post.Images, imageURL)
-					}
-				}
-			} else if isRedditImageURL(child.Data.URL) {
+				post.Images = append(post.Images, galleryImages(child.Data.GalleryData, child.Data.MediaMetadata)...)
+			} else if isRedditImageURL(child.Data.URL) && isValidImageURL(child.Data.URL) {
 				post.Images = append(post.Images, child.Data.URL)
 			}
 		}
 	}
 
-	// Second pass: Extract comments from the second element (t1 comments)
-	if len(apiResponse) >= 2 {
-		var rawResponse []json.RawMessage
-		if err := json.Unmarshal(bodyBytes, &rawResponse); err == nil && len(rawResponse) >= 2 {
-			var commentsListing struct {
-				Kind string `json:"kind"`
-				Data struct {
-					Children []json.RawMessage `json:"children"`
-				} `json:"data"`
-			}
-			if err := json.Unmarshal(rawResponse[1], &commentsListing); err == nil {
-				post.Comments = parseCommentListings(commentsListing.Data.Children)
-			}
-		}
+	// Extract comments from the second element (t1 comments)
+	if len(elements) >= 2 && !o.skipComments {
+		post.Comments, post.CommentsTruncated = parseCommentsElement(elements[1])
 	}
 
+	// The OP's suggested comment order applies unless the caller chose one.
+	// Only the comments are taken from the sorted response; if it cannot be
+	// fetched, the post keeps its comments in the default order.
+	if o.commentSort == "" && post.SuggestedSort != "" && !o.skipComments {
+		o.commentSort = post.SuggestedSort
+		sorted, err := e.fetchPostElements(ctx, subreddit, postID, o)
+		switch {
+		case err != nil:
+			newLogger(ctx, "post").Printf("suggested sort refetch failed: post=%s, sort=%s, error=%v", post.ID, o.commentSort, err)
+		case len(sorted) >= 2:
+			post.Comments, post.CommentsTruncated = parseCommentsElement(sorted[1])
+		}
+	}
 	return post, nil
 }
 
-// parseCommentListings parses comment listings from raw JSON messages.
-func parseCommentListings(children []json.RawMessage) []Comment {
-	comments := make([]Comment, 0, len(children))
-	for _, childRaw := range children {
-		var child struct {
-			Kind string `json:"kind"`
-			Data struct {
-				Body    string          `json:"body"`
-				Replies json.RawMessage `json:"replies"`
-			} `json:"data"`
+// ExtractRedditComments fetches the comments of a post using the default
+// Extractor.
+func ExtractRedditComments(ctx context.Context, redditURL string, opts ...ExtractOption) ([]Comment, error) {
+	return defaultExtractor.ExtractRedditComments(ctx, redditURL, opts...)
+}
+
+// ExtractRedditComments returns only the comment tree of the post at
+// redditURL, with the comment options of opts applied. It skips the post
+// fields, the HTML fallback and the post's suggested sort, so it is cheaper
+// than ExtractRedditPost for refreshing the comments of a known post. The
+// Comments of an empty thread are an empty, non-nil slice.
+func (e *Extractor) ExtractRedditComments(ctx context.Context, redditURL string, opts ...ExtractOption) (comments []Comment, err error) {
+	o := applyExtractOptions(opts)
+	ctx, span := e.tracer.Start(ctx, "extractor.ExtractRedditComments")
+	span.SetAttribute("reddit.url", redditURL)
+	defer func() {
+		span.SetAttribute("reddit.comment_count", len(comments))
+		endSpan(span, err)
+	}()
+
+	if err := e.ValidateRedditURL(redditURL); err != nil {
+		return nil, err
+	}
+	subreddit, postID, ok := parseRedditURL(redditURL)
+	if !ok {
+		return nil, errNotAPost
+	}
+	elements, err := e.fetchPostElements(ctx, subreddit, postID, o)
+	if err != nil {
+		return nil, err
+	}
+	if len(elements) < 2 {
+		return nil, fmt.Errorf("no comment listing in api response")
+	}
+	comments, _ = parseCommentsElement(elements[1])
+	if comments == nil {
+		comments = []Comment{}
+	}
+	return o.finishComments(comments), nil
+}
+
+// fetchPostElements fetches the post API response, confirming the age gate
+// if o allows it, and splits it into its post and comment listings.
+func (e *Extractor) fetchPostElements(ctx context.Context, subreddit, postID string, o extractOptions) ([]json.RawMessage, error) {
+	query := url.Values{}
+	if o.commentSort != "" {
+		query.Set("sort", o.commentSort)
+	}
+	jsonURL := redditAPIURL("json", query, "r", subreddit, "comments", postID)
+
+	bodyBytes, err := e.fetchPostBody(ctx, jsonURL, false)
+	var gated AgeGatedError
+	if errors.As(err, &gated) && o.viewNSFW {
+		bodyBytes, err = e.fetchPostBody(ctx, jsonURL, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	recordRawResponse(ctx, bodyBytes)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	// Split the response once; the post and comment listings are then
+	// decoded from their own elements.
+	var elements []json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &elements); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// parseCommentsElement parses the comment listing of a post API response.
+// A malformed listing yields no comments.
+func parseCommentsElement(raw json.RawMessage) (comments []Comment, truncated bool) {
+	var commentsListing struct {
+		Kind string `json:"kind"`
+		Data struct {
+			Children []json.RawMessage `json:"children"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &commentsListing); err != nil {
+		return nil, false
+	}
+	return parseCommentListings(commentsListing.Data.Children)
+}
+
+// warnContestMode logs that a comment sort requested for a contest mode
+// post may not have been applied, since Reddit randomizes those comments.
+func warnContestMode(logger *log.Logger, post *RedditPost, sort string) {
+	if post.ContestMode && sort != "" && len(post.Comments) > 0 {
+		logger.Printf("contest mode: post=%s, sort=%s, comment order and scores may be unreliable", post.ID, sort)
+	}
+}
+
+// fetchPostBody returns the body of the post API response at jsonURL.
+// Concurrent calls for the same URL share one upstream request: the first
+// caller makes it and the rest wait for its result, each bounded by its own
+// ctx. The shared request is detached from the first caller's cancellation
+// so that caller leaving early does not fail the others, but keeps its
+// deadline, see sharedFetchContext. Transfer stats are recorded only
+// against the caller that made the request.
+//
+// With over18 set the request carries the cookie that confirms Reddit's
+// over-18 interstitial; otherwise the interstitial yields an AgeGatedError.
+func (e *Extractor) fetchPostBody(ctx context.Context, jsonURL string, over18 bool) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := strings.ToLower(jsonURL)
+	if over18 {
+		key += " over18"
+	}
+	// Callers overriding the user agent must not share a request sent with
+	// another one.
+	if ua := UserAgentFromContext(ctx); ua != "" {
+		key += " ua=" + ua
+	}
+	ch := e.postFlight.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := e.sharedFetchContext(ctx)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(fetchCtx, "GET", jsonURL, nil)
+		if err != nil {
+			return nil, err
 		}
-		if err := json.Unmarshal(childRaw, &child); err != nil {
-			continue
+		e.setAPIHeaders(req)
+		if over18 {
+			req.AddCookie(&http.Cookie{Name: "over18", Value: "1"})
 		}
-		if child.Kind != "t1" {
-			continue
+
+		resp, err := e.doRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, unexpectedStatus(fetchCtx, resp)
 		}
 
-		comment := Comment{
-			Body: child.Data.Body,
+		// Read the entire response body first to enable multiple parsing passes
+		bodyBytes, err := readBody(fetchCtx, resp.Body)
+		if err != nil {
+			return nil, err
 		}
+		if isAgeGate(resp, bodyBytes) {
+			return nil, AgeGatedError{URL: jsonURL}
+		}
+		if err := checkJSONResponse(resp, bodyBytes); err != nil {
+			return nil, err
+		}
+		if err := parseUpstreamError(bodyBytes); err != nil {
+			return nil, err
+		}
+		return bodyBytes, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil
+	}
+}
+
+// sharedFetchContext returns the context of a request made on behalf of
+// ctx and shared with other callers. It ignores ctx's cancellation but ends
+// at ctx's deadline, or after the client's Timeout when ctx has none, so the
+// request and its retries never outlive the time the caller allowed.
+func (e *Extractor) sharedFetchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	if e.httpClient.Timeout > 0 {
+		return context.WithTimeout(detached, e.httpClient.Timeout)
+	}
+	return context.WithCancel(detached)
+}
+
+// contextReader fails reads once its context is done, so a cancelled
+// extraction stops consuming a large body instead of reading it to the end.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	default:
+	}
+	return r.r.Read(p)
+}
+
+// readBody reads body in full unless ctx is cancelled first.
+func readBody(ctx context.Context, body io.Reader) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	b, err := io.ReadAll(contextReader{ctx: ctx, r: body})
+	if stats := statsFromContext(ctx); stats != nil {
+		stats.bytesRead.Add(int64(len(b)))
+	}
+	return b, err
+}
+
+// maxCommentDepth is a hard safety cap on comment nesting. Code consuming
+// the tree, such as JSON encoding, recurses per level, so a crafted thread
+// must not produce an arbitrarily deep one.
+const maxCommentDepth = 500
+
+// parseCommentListings parses comment listings from raw JSON messages.
+// Reply levels are walked with an explicit stack rather than recursion, so
+// deeply nested threads cannot exhaust the goroutine stack. Replies beyond
+// maxCommentDepth levels are dropped and reported by truncated.
+func parseCommentListings(children []json.RawMessage) (comments []Comment, truncated bool) {
+	// commentLevel is a listing of sibling comments waiting to be parsed
+	// into dst, which always points at the Replies slice of an already
+	// placed comment, or at the top-level result.
+	type commentLevel struct {
+		children []json.RawMessage
+		dst      *[]Comment
+		depth    int
+	}
 
-		// Parse nested replies
-		if len(child.Data.Replies) > 0 && string(child.Data.Replies) != `""` && string(child.Data.Replies) != "" {
-			var replies struct {
+	stack := []commentLevel{{children: children, dst: &comments, depth: 1}}
+	for len(stack) > 0 {
+		level := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		// Preallocating the full length means the appends below never
+		// reallocate, so pointers to the placed comments stay valid.
+		*level.dst = make([]Comment, 0, len(level.children))
+		for _, childRaw := range level.children {
+			var child struct {
 				Kind string `json:"kind"`
 				Data struct {
-					Children []json.RawMessage `json:"children"`
+					ID            string          `json:"id"`
+					Name          string          `json:"name"`
+					Permalink     string          `json:"permalink"`
+					Author        string          `json:"author"`
+					Body          string          `json:"body"`
+					Score         int             `json:"score"`
+					Distinguished string          `json:"distinguished"`
+					Stickied      bool            `json:"stickied"`
+					CreatedUTC    float64         `json:"created_utc"`
+					IsSubmitter   bool            `json:"is_submitter"`
+					Edited        editedField     `json:"edited"`
+					Replies       json.RawMessage `json:"replies"`
 				} `json:"data"`
 			}
-			if err := json.Unmarshal(child.Data.Replies, &replies); err == nil {
-				if replies.Kind == "Listing" && len(replies.Data.Children) > 0 {
-					comment.Replies = parseCommentListings(replies.Data.Children)
+			if err := json.Unmarshal(childRaw, &child); err != nil {
+				continue
+			}
+			if child.Kind != "t1" {
+				continue
+			}
+
+			*level.dst = append(*level.dst, Comment{
+				ID:            commentID(child.Data.ID, child.Data.Name),
+				Permalink:     buildRedditPostLink(child.Data.Permalink),
+				Author:        child.Data.Author,
+				Body:          child.Data.Body,
+				Score:         child.Data.Score,
+				Distinguished: child.Data.Distinguished,
+				Stickied:      child.Data.Stickied,
+				CreatedUTC:    int64(child.Data.CreatedUTC),
+				IsSubmitter:   child.Data.IsSubmitter,
+				Edited:        child.Data.Edited.Edited,
+				EditedAt:      child.Data.Edited.formattedAt(),
+			})
+
+			// Queue nested replies
+			if len(child.Data.Replies) > 0 && string(child.Data.Replies) != `""` {
+				var replies struct {
+					Kind string `json:"kind"`
+					Data struct {
+						Children []json.RawMessage `json:"children"`
+					} `json:"data"`
+				}
+				if err := json.Unmarshal(child.Data.Replies, &replies); err == nil {
+					if replies.Kind == "Listing" && len(replies.Data.Children) > 0 {
+						if level.depth >= maxCommentDepth {
+							truncated = true
+							continue
+						}
+						placed := &(*level.dst)[len(*level.dst)-1]
+						stack = append(stack, commentLevel{children: replies.Data.Children, dst: &placed.Replies, depth: level.depth + 1})
+					}
 				}
 			}
 		}
-
-		comments = append(comments, comment)
 	}
-	return comments
+	return comments, truncated
 }
 
-func extractRedditPostFromHTML(ctx context.Context, redditURL string) (*RedditPost, error) {
-	c := colly.NewCollector()
-	c.UserAgent = htmlUserAgent
-	c.SetRequestTimeout(12 * time.Second)
+func (e *Extractor) extractRedditPostFromHTML(ctx context.Context, redditURL string) (*RedditPost, error) {
+	c := e.newCollector(ctx)
 
 	post := &RedditPost{
 		Images: []string{},
@@ -321,11 +955,30 @@ func extractRedditPostFromHTML(ctx context.Context, redditURL string) (*RedditPo
 
 	c.OnHTML(`img[src*="preview.redd.it"]`, func(e *colly.HTMLElement) {
 		src := e.Attr("src")
-		if src != "" && !strings.Contains(src, "avatar") {
+		if isValidImageURL(src) && !strings.Contains(src, "avatar") {
 			post.Images = append(post.Images, src)
 		}
 	})
 
+	// Replies are nested inside their parent's element, and OnHTML visits
+	// elements in document order, so the depth attributes are enough to
+	// rebuild the tree afterwards.
+	var comments []htmlComment
+	c.OnHTML(`shreddit-comment`, func(e *colly.HTMLElement) {
+		depth, _ := strconv.Atoi(e.Attr("depth"))
+		if depth > maxCommentDepth {
+			return
+		}
+		score, _ := strconv.Atoi(e.Attr("score"))
+		comments = append(comments, htmlComment{depth: depth, comment: Comment{
+			ID:        commentID("", e.Attr("thingid")),
+			Permalink: buildRedditPostLink(e.Attr("permalink")),
+			Author:    e.Attr("author"),
+			Body:      htmlCommentBody(e.DOM.ChildrenFiltered(`div[slot="comment"]`)),
+			Score:     score,
+		}})
+	})
+
 	c.OnError(func(r *colly.Response, err error) {
 		_ = r
 	})
@@ -341,5 +994,21 @@ func extractRedditPostFromHTML(ctx context.Context, redditURL string) (*RedditPo
 	}
 
 	c.Wait()
+	post.Comments = nestHTMLComments(comments)
 	return post, nil
 }
+
+// htmlCommentBody returns the text of a comment's body element, with its
+// paragraphs separated by blank lines as in the API's markdown.
+func htmlCommentBody(body *goquery.Selection) string {
+	var paragraphs []string
+	body.Find("p").Each(func(_ int, p *goquery.Selection) {
+		if text := strings.TrimSpace(p.Text()); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	})
+	if len(paragraphs) == 0 {
+		return strings.TrimSpace(body.Text())
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -3,9 +3,93 @@ package extractor
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
+// roundTripFunc adapts a function into an http.RoundTripper so tests can
+// serve canned Reddit responses without touching the network.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func cannedResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// apiTransport answers requests for the Reddit API with api and sends any
+// other request, such as an HTML fetch from a test server, on to that server.
+func apiTransport(api roundTripFunc) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		if req.URL.Scheme+"://"+req.URL.Host == redditAPIBase {
+			return api(req)
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	}
+}
+
+// fixtureExtractor returns an Extractor whose every API request is answered
+// with body.
+func fixtureExtractor(body string, opts ...Option) *Extractor {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return cannedResponse(req, http.StatusOK, body), nil
+	})}
+	return mustNewExtractor(append([]Option{WithHTTPClient(client)}, opts...)...)
+}
+
+const testPostURL = "https://www.reddit.com/r/golang/comments/abc123/fixture_post/"
+
+const postFixture = `[
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"title": "Fixture post", "author": "gopher", "score": 42, "num_comments": 1, "selftext": "hello"}}
+	]}},
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t1", "data": {"body": "first!", "replies": ""}}
+	]}}
+]`
+
+func TestWithHTTPClient(t *testing.T) {
+	var requested string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.String()
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	post, err := e.ExtractRedditPost(context.Background(), "https://www.reddit.com/r/golang/comments/abc123/fixture_post/")
+	if err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	if want := "https://www.reddit.com/r/golang/comments/abc123.json"; requested != want {
+		t.Errorf("requested %q, want %q", requested, want)
+	}
+	if post.Title != "Fixture post" || post.Author != "gopher" || post.Score != "42" {
+		t.Errorf("unexpected post: %+v", post)
+	}
+	if post.ScoreInt != 42 || post.CommentCountInt != 1 || post.CommentCount != "1" {
+		t.Errorf("numeric counts = %d/%d, want 42/1 matching %q/%q", post.ScoreInt, post.CommentCountInt, post.Score, post.CommentCount)
+	}
+	if len(post.Comments) != 1 || post.Comments[0].Body != "first!" {
+		t.Errorf("unexpected comments: %+v", post.Comments)
+	}
+}
+
 func TestExtractRedditPostWithComments(t *testing.T) {
 	// Using the URL provided by the user for testing
 	redditURL := "https://www.reddit.com/r/RetroFuturism/comments/1pwtza4/ed_valigursky_ii/"
@@ -72,3 +156,339 @@ func TestExtractRedditPostWithComments(t *testing.T) {
 		}
 	}
 }
+
+func TestExtractRedditPostFromAPIHonorsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// The request completes, but the caller gives up before the body is read.
+		cancel()
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	_, err := e.extractRedditPostFromAPI(ctx, "https://www.reddit.com/r/golang/comments/abc123/fixture_post/", extractOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestReadBodyStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := readBody(ctx, strings.NewReader("{}")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestExtractRedditPostRejectsNonPostURL(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request to %s", req.URL)
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	_, err := e.ExtractRedditPost(context.Background(), "https://www.reddit.com/r/golang/")
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("err = %v, want ValidationError", err)
+	}
+}
+
+func TestExtractRedditPostFlags(t *testing.T) {
+	fixture := `[
+		{"kind": "Listing", "data": {"children": [
+			{"kind": "t3", "data": {"title": "old news", "edited": 1700000000, "locked": true, "archived": true}}
+		]}},
+		{"kind": "Listing", "data": {"children": []}}
+	]`
+	post, err := fixtureExtractor(fixture).extractRedditPostFromAPI(context.Background(), testPostURL, extractOptions{})
+	if err != nil {
+		t.Fatalf("extractRedditPostFromAPI failed: %v", err)
+	}
+	if !post.Edited || post.EditedAt == "" || !post.Locked || !post.Archived {
+		t.Errorf("unexpected flags: %+v", post)
+	}
+
+	post, err = fixtureExtractor(postFixture).extractRedditPostFromAPI(context.Background(), testPostURL, extractOptions{})
+	if err != nil {
+		t.Fatalf("extractRedditPostFromAPI failed: %v", err)
+	}
+	if post.Edited || post.EditedAt != "" || post.Locked || post.Archived {
+		t.Errorf("expected no flags, got %+v", post)
+	}
+}
+
+func TestValidateRedditURLAllowedHosts(t *testing.T) {
+	testCases := []struct {
+		name    string
+		opts    []Option
+		url     string
+		wantErr bool
+	}{
+		{name: "default www", url: "https://www.reddit.com/r/golang/comments/abc123/x/"},
+		{name: "default old", url: "https://old.reddit.com/r/golang/comments/abc123/x/"},
+		{name: "default uppercase host", url: "https://WWW.Reddit.com/r/golang/comments/abc123/x/"},
+		{name: "default rejects other host", url: "https://example.com/r/golang/comments/abc123/x/", wantErr: true},
+		{name: "default rejects lookalike", url: "https://www.reddit.com.evil.example/r/golang/", wantErr: true},
+		{name: "custom allows np", opts: []Option{WithAllowedHosts([]string{"www.reddit.com", "np.reddit.com"})}, url: "https://np.reddit.com/r/golang/comments/abc123/x/"},
+		{name: "custom rejects old", opts: []Option{WithAllowedHosts([]string{"www.reddit.com"})}, url: "https://old.reddit.com/r/golang/comments/abc123/x/", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := mustNewExtractor(tc.opts...).ValidateRedditURL(tc.url)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidateRedditURL() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// sourceTestSetup returns an Extractor whose API requests are answered with
+// apiBody, the URL of a local post page serving a page titled "From HTML",
+// and counters for the API and HTML requests made.
+func sourceTestSetup(t *testing.T, apiBody string) (*Extractor, string, *atomic.Int64, *atomic.Int64) {
+	t.Helper()
+	var apiCalls, htmlCalls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		htmlCalls.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, "<html><body><h1>From HTML</h1></body></html>")
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: apiTransport(func(req *http.Request) (*http.Response, error) {
+		apiCalls.Add(1)
+		return cannedResponse(req, http.StatusOK, apiBody), nil
+	})}
+	u, _ := url.Parse(server.URL)
+	e := mustNewExtractor(WithHTTPClient(client), WithAllowedHosts([]string{u.Hostname()}))
+	return e, server.URL + "/r/golang/comments/abc123/fixture_post/", &apiCalls, &htmlCalls
+}
+
+func TestExtractRedditPostHTMLOnly(t *testing.T) {
+	e, postURL, apiCalls, htmlCalls := sourceTestSetup(t, postFixture)
+
+	post, err := e.ExtractRedditPostWithOptions(context.Background(), postURL, HTMLOnly())
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if post.Title != "From HTML" || post.Source != SourceHTML {
+		t.Errorf("title = %q, source = %q, want the HTML title and source", post.Title, post.Source)
+	}
+	if apiCalls.Load() != 0 || htmlCalls.Load() != 1 {
+		t.Errorf("api calls = %d, html calls = %d, want 0 and 1", apiCalls.Load(), htmlCalls.Load())
+	}
+}
+
+func TestExtractRedditPostAPIOnly(t *testing.T) {
+	e, postURL, apiCalls, htmlCalls := sourceTestSetup(t, `[{"kind": "Listing", "data": {"children": []}}]`)
+
+	if _, err := e.ExtractRedditPostWithOptions(context.Background(), postURL, APIOnly()); err == nil {
+		t.Fatal("expected an error for an empty API result")
+	}
+	if apiCalls.Load() != 1 || htmlCalls.Load() != 0 {
+		t.Errorf("api calls = %d, html calls = %d, want 1 and 0", apiCalls.Load(), htmlCalls.Load())
+	}
+}
+
+func TestExtractRedditPostFallsBackToHTML(t *testing.T) {
+	e, postURL, apiCalls, htmlCalls := sourceTestSetup(t, `[{"kind": "Listing", "data": {"children": []}}]`)
+
+	post, err := e.ExtractRedditPost(context.Background(), postURL)
+	if err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	if post.Title != "From HTML" || post.Source != SourceHTML || apiCalls.Load() != 1 || htmlCalls.Load() != 1 {
+		t.Errorf("title = %q, source = %q, api calls = %d, html calls = %d", post.Title, post.Source, apiCalls.Load(), htmlCalls.Load())
+	}
+}
+
+func TestPreferHTMLWhenIncomplete(t *testing.T) {
+	var htmlCalls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		htmlCalls.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, `<html><body><h1>From HTML</h1>
+			<faceplate-hovercard><faceplate-tracker><a>html_author</a></faceplate-tracker></faceplate-hovercard>
+			<shreddit-post-text-body><p>Body from HTML</p></shreddit-post-text-body>
+			<img src="https://preview.redd.it/html.jpg"></body></html>`)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: apiTransport(func(req *http.Request) (*http.Response, error) {
+		return cannedResponse(req, http.StatusOK, `[
+			{"kind": "Listing", "data": {"children": [
+				{"kind": "t3", "data": {"title": "From API", "author": "gopher", "score": 7, "created_utc": 1700000000}}
+			]}},
+			{"kind": "Listing", "data": {"children": []}}
+		]`), nil
+	})}
+	u, _ := url.Parse(server.URL)
+	e := mustNewExtractor(WithHTTPClient(client), WithAllowedHosts([]string{u.Hostname()}))
+	postURL := server.URL + "/r/golang/comments/abc123/fixture_post/"
+
+	post, err := e.ExtractRedditPostWithOptions(context.Background(), postURL)
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if post.Content != "" || post.Source != SourceAPI || htmlCalls.Load() != 0 {
+		t.Fatalf("content = %q, source = %q, html calls = %d, want an API-only result without the option", post.Content, post.Source, htmlCalls.Load())
+	}
+
+	post, err = e.ExtractRedditPostWithOptions(context.Background(), postURL, PreferHTMLWhenIncomplete())
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if post.Title != "From API" || post.Author != "gopher" || post.Score != "7" {
+		t.Errorf("API fields were overwritten: %+v", post)
+	}
+	if post.Source != SourceMerged {
+		t.Errorf("source = %q, want merged", post.Source)
+	}
+	if post.Content != "Body from HTML" {
+		t.Errorf("content = %q, want it filled from HTML", post.Content)
+	}
+	if len(post.Images) != 1 || post.Images[0] != "https://preview.redd.it/html.jpg" {
+		t.Errorf("images = %v, want the HTML image", post.Images)
+	}
+}
+
+func TestConcurrentExtractionsShareOneRequest(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		<-release
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	const n = 10
+	var started, done sync.WaitGroup
+	started.Add(n)
+	done.Add(n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			post, err := e.ExtractRedditPost(context.Background(), testPostURL)
+			if err == nil && post.Title != "Fixture post" {
+				err = errors.New("unexpected title " + post.Title)
+			}
+			errs <- err
+		}()
+	}
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("ExtractRedditPost failed: %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("upstream requests = %d, want 1", got)
+	}
+}
+
+func TestSharedFetchKeepsCallerDeadline(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		deadline, hasDeadline = req.Context().Deadline()
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})
+
+	e := mustNewExtractor(WithHTTPClient(&http.Client{Transport: transport}))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	want, _ := ctx.Deadline()
+	if _, err := e.ExtractRedditPost(ctx, testPostURL); err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	if !hasDeadline || !deadline.Equal(want) {
+		t.Errorf("request deadline = %v (set %v), want the caller's %v", deadline, hasDeadline, want)
+	}
+
+	e = mustNewExtractor(WithHTTPClient(&http.Client{Timeout: 30 * time.Second, Transport: transport}))
+	start := time.Now()
+	if _, err := e.ExtractRedditPost(context.Background(), testPostURL); err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	if left := deadline.Sub(start); !hasDeadline || left < 29*time.Second || left > 31*time.Second {
+		t.Errorf("request deadline %v after the call (set %v), want the client's 30s timeout", left, hasDeadline)
+	}
+}
+
+func TestMergeRedditPosts(t *testing.T) {
+	api := &RedditPost{
+		Title:    "From API",
+		Author:   "gopher",
+		Score:    "7",
+		Images:   []string{"https://i.redd.it/a.jpg"},
+		Comments: []Comment{{Body: "api comment"}},
+	}
+	html := &RedditPost{
+		Title:         "From HTML",
+		Author:        "html_author",
+		PublishedTime: "2023-11-14T22:13:20Z",
+		Score:         "9",
+		CommentCount:  "3",
+		Content:       "Body from HTML",
+		Images:        []string{"https://i.redd.it/a.jpg", "https://preview.redd.it/b.jpg"},
+		Stickied:      true,
+		Embed:         &Embed{Provider: "YouTube"},
+		Comments:      []Comment{{Body: "html comment"}},
+	}
+
+	got := mergeRedditPosts(api, html)
+	if got != api {
+		t.Fatal("expected the primary post to be returned")
+	}
+	cases := []struct{ field, got, want string }{
+		{"title", got.Title, "From API"},
+		{"author", got.Author, "gopher"},
+		{"score", got.Score, "7"},
+		{"published_time", got.PublishedTime, "2023-11-14T22:13:20Z"},
+		{"comment_count", got.CommentCount, "3"},
+		{"content", got.Content, "Body from HTML"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+		}
+	}
+	if len(got.Images) != 2 || got.Images[0] != "https://i.redd.it/a.jpg" || got.Images[1] != "https://preview.redd.it/b.jpg" {
+		t.Errorf("images = %v, want primary first and duplicates dropped", got.Images)
+	}
+	if got.Stickied {
+		t.Error("boolean flags should stay as the primary has them")
+	}
+	if got.Embed == nil || got.Embed.Provider != "YouTube" {
+		t.Errorf("embed = %+v, want it taken from the secondary", got.Embed)
+	}
+	if len(got.Comments) != 1 || got.Comments[0].Body != "api comment" {
+		t.Errorf("comments = %+v, want the primary comments", got.Comments)
+	}
+}
+
+func TestMergeRedditPostsNil(t *testing.T) {
+	post := &RedditPost{Title: "x"}
+	if mergeRedditPosts(nil, post) != post || mergeRedditPosts(post, nil) != post {
+		t.Error("a nil side should yield the other post")
+	}
+}
+
+func BenchmarkExtractRedditPostFromAPI(b *testing.B) {
+	e := fixtureExtractor(largeThreadFixture(200))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := e.extractRedditPostFromAPI(context.Background(), testPostURL, extractOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package extractor
+
+// Gildings counts the legacy awards of a post, the compact form of its
+// awards that Reddit still reports. Awards other than these three are not
+// counted.
+type Gildings struct {
+	Silver   int `json:"silver,omitempty"`
+	Gold     int `json:"gold,omitempty"`
+	Platinum int `json:"platinum,omitempty"`
+}
+
+// redditGildings is the gildings object of a post, award counts keyed by
+// award ID.
+type redditGildings map[string]int
+
+// buildGildings converts gildings into Gildings, returning nil for posts
+// without any of the legacy awards.
+func buildGildings(gildings redditGildings) *Gildings {
+	g := Gildings{
+		Silver:   gildings["gid_1"],
+		Gold:     gildings["gid_2"],
+		Platinum: gildings["gid_3"],
+	}
+	if g == (Gildings{}) {
+		return nil
+	}
+	return &g
+}
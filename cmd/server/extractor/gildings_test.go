@@ -0,0 +1,54 @@
+package extractor
+
+import (
+	"context"
+	"testing"
+)
+
+const gildedPostFixture = `[
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {
+			"title": "Gilded post",
+			"gildings": {"gid_1": 3, "gid_2": 1, "award_123": 4}
+		}}
+	]}},
+	{"kind": "Listing", "data": {"children": []}}
+]`
+
+func TestExtractRedditPostGildings(t *testing.T) {
+	post, err := fixtureExtractor(gildedPostFixture).extractRedditPostFromAPI(context.Background(), testPostURL, extractOptions{})
+	if err != nil {
+		t.Fatalf("extractRedditPostFromAPI failed: %v", err)
+	}
+	if post.Gildings == nil || *post.Gildings != (Gildings{Silver: 3, Gold: 1}) {
+		t.Errorf("gildings = %+v, want 3 silver and 1 gold", post.Gildings)
+	}
+
+	post, err = fixtureExtractor(postFixture).extractRedditPostFromAPI(context.Background(), testPostURL, extractOptions{})
+	if err != nil {
+		t.Fatalf("extractRedditPostFromAPI failed: %v", err)
+	}
+	if post.Gildings != nil {
+		t.Errorf("expected nil gildings, got %+v", post.Gildings)
+	}
+}
+
+func TestSubredditPostGildings(t *testing.T) {
+	body := `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"id": "a", "title": "gilded", "permalink": "/r/golang/comments/a/x/", "gildings": {"gid_3": 2}}},
+		{"kind": "t3", "data": {"id": "b", "title": "plain", "permalink": "/r/golang/comments/b/x/", "gildings": {}}}
+	]}}`
+	resp, err := fixtureExtractor(body).ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if len(resp.Posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(resp.Posts))
+	}
+	if g := resp.Posts[0].Gildings; g == nil || *g != (Gildings{Platinum: 2}) {
+		t.Errorf("gildings = %+v, want 2 platinum", g)
+	}
+	if g := resp.Posts[1].Gildings; g != nil {
+		t.Errorf("expected nil gildings for an empty object, got %+v", g)
+	}
+}
@@ -0,0 +1,130 @@
+package extractor
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares the indented JSON encoding of v with
+// testdata/<name>.golden, or rewrites the file when -update is set.
+func checkGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("JSON output changed; review it and run go test -update if intended.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestRedditPostGolden pins the JSON field names of a fully populated
+// RedditPost.
+func TestRedditPostGolden(t *testing.T) {
+	post := RedditPost{
+		ID:            "abc123",
+		Title:         "Fixture post",
+		Author:        "gopher",
+		PublishedTime: "2024-05-29T16:26:40Z",
+		Score:         "42",
+		CommentCount:  "2",
+		Content:       "hello",
+		Images:        []string{"https://i.redd.it/a.jpg"},
+		Embed:         &Embed{Provider: "YouTube", Title: "A talk", ThumbnailURL: "https://i.ytimg.com/vi/x/hqdefault.jpg", HTML: "<iframe></iframe>"},
+		Gildings:      &Gildings{Silver: 2, Gold: 1, Platinum: 1},
+		Poll: &Poll{
+			Options:       []PollOption{{Text: "yes", Votes: 3}, {Text: "no", Votes: 1}},
+			TotalVotes:    4,
+			VotingEndsAt:  "2024-06-01T00:00:00Z",
+			UserSelection: "yes",
+		},
+		Distinguished: "moderator",
+		Stickied:      true,
+		Edited:        true,
+		EditedAt:      "2024-05-29T17:00:00Z",
+		Locked:        true,
+		Archived:      true,
+		Crossposts:    1,
+		ViewCount:     1000,
+		SuggestedSort: "new",
+		ContestMode:   true,
+		Comments: []Comment{{
+			ID:            "c1",
+			Permalink:     "https://www.reddit.com/r/golang/comments/abc123/fixture_post/c1/",
+			Author:        "gopher",
+			Body:          "first!",
+			Score:         5,
+			Distinguished: "admin",
+			Stickied:      true,
+			CreatedUTC:    1716999000,
+			IsSubmitter:   true,
+			Edited:        true,
+			EditedAt:      "2024-05-29T18:00:00Z",
+			Replies:       []Comment{{Body: "second", Score: 1}},
+		}},
+		CommentsTruncated: true,
+		Source:            SourceMerged,
+		ScoreInt:          42,
+		CommentCountInt:   2,
+	}
+	checkGolden(t, "reddit_post", post)
+	checkGolden(t, "reddit_post_minimal", RedditPost{Title: "Fixture post"})
+}
+
+// TestSubredditListResponseGolden pins the JSON field names of a fully
+// populated SubredditListResponse.
+func TestSubredditListResponseGolden(t *testing.T) {
+	resp := SubredditListResponse{
+		Source: SourceJSON,
+		Posts: []SubredditPost{{
+			ID:            "abc123",
+			Title:         "Fixture post",
+			Subreddit:     "golang",
+			Author:        "gopher",
+			PublishedTime: "2024-05-29 16:26:40",
+			ImageURLs:     []string{"https://i.redd.it/a.jpg"},
+			Images:        []ImageInfo{{URL: "https://i.redd.it/a.jpg", Width: 1920, Height: 1080, Type: "image/jpg"}},
+			PostLink:      "https://www.reddit.com/r/golang/comments/abc123/fixture_post/",
+			Score:         42,
+			Comments:      2,
+			ExternalLink:  "https://go.dev/",
+			Embed:         &Embed{Provider: "YouTube"},
+			Gildings:      &Gildings{Gold: 1},
+			Distinguished: "moderator",
+			Stickied:      true,
+			IsSelf:        true,
+			Flair:         "discussion",
+			Crossposts:    1,
+			ViewCount:     1000,
+
+			ContentPreview: "hello",
+		}},
+		NextAfter:     "t3_abc123",
+		HasMore:       true,
+		Partial:       true,
+		PartialError:  "unexpected EOF",
+		SeenSkipped:   3,
+		FieldCoverage: map[string]int{"posts": 1, "title": 1},
+	}
+	checkGolden(t, "subreddit_list_response", resp)
+	checkGolden(t, "subreddit_list_response_minimal", SubredditListResponse{Posts: []SubredditPost{}})
+}
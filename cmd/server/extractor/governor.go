@@ -0,0 +1,32 @@
+package extractor
+
+import "time"
+
+// Governor is a request rate ceiling shared by every Extractor pointed at
+// it with WithGovernor, so the combined outbound Reddit traffic of a process
+// stays under one limit however many Extractors and goroutines it runs.
+// Each request waits for its turn on the clock of the Extractor sending it.
+// It is safe for concurrent use.
+type Governor struct {
+	gate *intervalGate
+}
+
+// NewGovernor returns a Governor allowing at most qps requests per second
+// across all the Extractors sharing it, spaced evenly. qps <= 0 returns a
+// Governor that does not limit.
+func NewGovernor(qps float64) *Governor {
+	if qps <= 0 {
+		return &Governor{}
+	}
+	interval := time.Duration(float64(time.Second) / qps)
+	return &Governor{gate: &intervalGate{interval: interval}}
+}
+
+// reserve takes the next request slot, given the current time now, and
+// returns how long the request has to wait for it.
+func (g *Governor) reserve(now time.Time) time.Duration {
+	if g.gate == nil {
+		return 0
+	}
+	return g.gate.reserve(now)
+}
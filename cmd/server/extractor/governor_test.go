@@ -0,0 +1,82 @@
+package extractor
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGovernorSharedAcrossExtractors(t *testing.T) {
+	const qps, perExtractor = 20, 3
+	interval := time.Second / qps
+
+	var mu sync.Mutex
+	var sent []time.Time
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		sent = append(sent, time.Now())
+		mu.Unlock()
+		return cannedResponse(req, http.StatusOK, `{"kind": "Listing", "data": {"children": []}}`), nil
+	})}
+	governor := NewGovernor(qps)
+	extractors := []*Extractor{
+		mustNewExtractor(WithHTTPClient(client), WithGovernor(governor)),
+		mustNewExtractor(WithHTTPClient(client), WithGovernor(governor)),
+	}
+
+	var wg sync.WaitGroup
+	for _, e := range extractors {
+		for i := 0; i < perExtractor; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, ""); err != nil {
+					t.Errorf("ExtractSubredditPosts failed: %v", err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	n := len(extractors) * perExtractor
+	if len(sent) != n {
+		t.Fatalf("got %d requests, want %d", len(sent), n)
+	}
+	sort.Slice(sent, func(i, j int) bool { return sent[i].Before(sent[j]) })
+	// n requests at the ceiling span n-1 intervals; allow for timer
+	// granularity on the last wait.
+	if span, want := sent[n-1].Sub(sent[0]), time.Duration(n-1)*interval; span < want-10*time.Millisecond {
+		t.Fatalf("%d requests took %v, want at least %v for %d qps combined", n, span, want, qps)
+	}
+}
+
+func TestGovernorUsesExtractorClock(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return cannedResponse(req, http.StatusOK, `{"kind": "Listing", "data": {"children": []}}`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithClock(clock), WithGovernor(NewGovernor(1)))
+
+	for i := 0; i < 3; i++ {
+		if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, ""); err != nil {
+			t.Fatalf("ExtractSubredditPosts failed: %v", err)
+		}
+	}
+	if got, want := clock.Now().Sub(start), 2*time.Second; got != want {
+		t.Fatalf("clock advanced %v, want %v", got, want)
+	}
+}
+
+func TestGovernorUnlimited(t *testing.T) {
+	g := NewGovernor(0)
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		if d := g.reserve(now); d != 0 {
+			t.Fatalf("reserve %d waits %v, want 0", i, d)
+		}
+	}
+}
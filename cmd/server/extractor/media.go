@@ -0,0 +1,222 @@
+package extractor
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// previewImagePathRE matches the path of a preview.redd.it URL that names
+// the underlying i.redd.it file directly.
+var previewImagePathRE = regexp.MustCompile(`^/[A-Za-z0-9]+\.(?:jpg|jpeg|png|gif|webp)$`)
+
+// Embed describes the oEmbed preview Reddit attaches to link posts for
+// providers such as YouTube or Imgur.
+type Embed struct {
+	Provider     string `json:"provider"`
+	Title        string `json:"title,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	HTML         string `json:"html,omitempty"`
+}
+
+// redditMedia is the media/secure_media object of a post.
+type redditMedia struct {
+	Type   string `json:"type"`
+	Oembed *struct {
+		ProviderName string `json:"provider_name"`
+		Title        string `json:"title"`
+		ThumbnailURL string `json:"thumbnail_url"`
+		HTML         string `json:"html"`
+	} `json:"oembed"`
+}
+
+// buildEmbed returns the post's embed, preferring secure_media over media.
+// It returns nil when neither carries oEmbed data.
+func buildEmbed(secureMedia, media *redditMedia) *Embed {
+	m := secureMedia
+	if m == nil || m.Oembed == nil {
+		m = media
+	}
+	if m == nil || m.Oembed == nil {
+		return nil
+	}
+	provider := strings.TrimSpace(m.Oembed.ProviderName)
+	if provider == "" {
+		provider = m.Type
+	}
+	return &Embed{
+		Provider:     provider,
+		Title:        m.Oembed.Title,
+		ThumbnailURL: strings.ReplaceAll(m.Oembed.ThumbnailURL, "&amp;", "&"),
+		HTML:         html.UnescapeString(m.Oembed.HTML),
+	}
+}
+
+// ImageInfo is an extracted image with its dimensions and MIME type, such
+// as image/jpg. Reddit reports the type only for gallery images and the
+// dimensions only for gallery and preview images; missing ones are zero.
+type ImageInfo struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// imageURLs returns the URLs of images.
+func imageURLs(images []ImageInfo) []string {
+	if images == nil {
+		return nil
+	}
+	urls := make([]string, len(images))
+	for i, img := range images {
+		urls[i] = img.URL
+	}
+	return urls
+}
+
+// redditImageSource is an image URL with its size, as found in
+// media_metadata[].s and preview.images[].source.
+type redditImageSource struct {
+	U      string `json:"u"`
+	URL    string `json:"url"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// redditMediaItem is one entry of a gallery post's media_metadata.
+type redditMediaItem struct {
+	Status string            `json:"status"`
+	E      string            `json:"e"`
+	M      string            `json:"m"`
+	S      redditImageSource `json:"s"`
+}
+
+// redditGalleryData lists the media IDs of a gallery in display order.
+type redditGalleryData struct {
+	Items []struct {
+		MediaID string `json:"media_id"`
+	} `json:"items"`
+}
+
+// galleryImages returns the valid image URLs of a gallery in display order.
+func galleryImages(gallery *redditGalleryData, metadata map[string]redditMediaItem) []string {
+	return imageURLs(galleryImageInfo(gallery, metadata, false))
+}
+
+// galleryImageInfo returns the valid images of a gallery in display order.
+// media_metadata is an unordered object, so the order comes from
+// gallery_data; entries it does not list follow, sorted by ID, so the output
+// is stable either way. With durable set, images use their i.redd.it URL
+// where durableGalleryURL can derive it instead of the signed one in
+// media_metadata.
+func galleryImageInfo(gallery *redditGalleryData, metadata map[string]redditMediaItem, durable bool) []ImageInfo {
+	ids := make([]string, 0, len(metadata))
+	listed := make(map[string]bool, len(metadata))
+	if gallery != nil {
+		for _, item := range gallery.Items {
+			if _, ok := metadata[item.MediaID]; ok && !listed[item.MediaID] {
+				listed[item.MediaID] = true
+				ids = append(ids, item.MediaID)
+			}
+		}
+	}
+	var rest []string
+	for id := range metadata {
+		if !listed[id] {
+			rest = append(rest, id)
+		}
+	}
+	sort.Strings(rest)
+	ids = append(ids, rest...)
+
+	var images []ImageInfo
+	for _, id := range ids {
+		media := metadata[id]
+		if media.Status != "valid" || !strings.EqualFold(media.E, "Image") {
+			continue
+		}
+		imageURL := strings.ReplaceAll(media.S.U, "&amp;", "&")
+		if durable {
+			if durableURL := durableGalleryURL(id, media.M); durableURL != "" {
+				imageURL = durableURL
+			}
+		}
+		if isValidImageURL(imageURL) {
+			images = append(images, ImageInfo{URL: imageURL, Width: media.S.X, Height: media.S.Y, Type: media.M})
+		}
+	}
+	return images
+}
+
+// isValidImageURL reports whether raw is an absolute http(s) URL. Image
+// URLs end up in browsers, so anything else, such as a javascript: URL, is
+// dropped rather than passed through.
+func isValidImageURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+// limitImages truncates images to at most max entries; max <= 0 means no
+// limit.
+func limitImages(images []string, max int) []string {
+	if max > 0 && len(images) > max {
+		return images[:max]
+	}
+	return images
+}
+
+// rewritePreviewURL maps a preview.redd.it URL, which is signed and expires,
+// to the stable i.redd.it URL of the same image. That mapping is only
+// derivable when the path is just the image file name; any other URL,
+// including slugged preview names and external-preview.redd.it, is
+// returned untouched.
+func rewritePreviewURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || !strings.EqualFold(u.Hostname(), "preview.redd.it") || !previewImagePathRE.MatchString(u.Path) {
+		return raw
+	}
+	return "https://i.redd.it" + u.Path
+}
+
+// durableGalleryURL returns the i.redd.it URL of the gallery image with the
+// given media ID and MIME type, such as image/jpg, or "" when the two do not
+// name an image file.
+func durableGalleryURL(mediaID, mimeType string) string {
+	ext, ok := strings.CutPrefix(strings.ToLower(mimeType), "image/")
+	if !ok {
+		return ""
+	}
+	path := "/" + mediaID + "." + ext
+	if !previewImagePathRE.MatchString(path) {
+		return ""
+	}
+	return "https://i.redd.it" + path
+}
+
+// rewritePreviewURLs applies rewritePreviewURL to each image in place.
+func rewritePreviewURLs(images []string) []string {
+	for i, img := range images {
+		images[i] = rewritePreviewURL(img)
+	}
+	return images
+}
+
+// filterImageSize keeps the images at least minWidth by minHeight. Images
+// whose size Reddit did not report cannot be checked and are dropped too.
+func filterImageSize(images []ImageInfo, minWidth, minHeight int) []ImageInfo {
+	kept := images[:0]
+	for _, img := range images {
+		if img.Width > 0 && img.Height > 0 && img.Width >= minWidth && img.Height >= minHeight {
+			kept = append(kept, img)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,331 @@
+package extractor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+const youtubePostFixture = `[
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {
+			"title": "Great talk",
+			"url": "https://www.youtube.com/watch?v=abc",
+			"permalink": "/r/golang/comments/abc123/great_talk/",
+			"secure_media": {"type": "youtube.com", "oembed": {
+				"provider_name": "YouTube",
+				"title": "GopherCon keynote",
+				"thumbnail_url": "https://i.ytimg.com/vi/abc/hqdefault.jpg?a=1&amp;b=2",
+				"html": "&lt;iframe src=\"https://www.youtube.com/embed/abc\"&gt;&lt;/iframe&gt;"
+			}}
+		}}
+	]}},
+	{"kind": "Listing", "data": {"children": []}}
+]`
+
+func TestExtractRedditPostEmbed(t *testing.T) {
+	post, err := fixtureExtractor(youtubePostFixture).extractRedditPostFromAPI(context.Background(), testPostURL, extractOptions{})
+	if err != nil {
+		t.Fatalf("extractRedditPostFromAPI failed: %v", err)
+	}
+	want := Embed{
+		Provider:     "YouTube",
+		Title:        "GopherCon keynote",
+		ThumbnailURL: "https://i.ytimg.com/vi/abc/hqdefault.jpg?a=1&b=2",
+		HTML:         `<iframe src="https://www.youtube.com/embed/abc"></iframe>`,
+	}
+	if post.Embed == nil || *post.Embed != want {
+		t.Fatalf("embed = %+v, want %+v", post.Embed, want)
+	}
+}
+
+func TestParseListingPostsEmbed(t *testing.T) {
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"title": "video", "permalink": "/r/golang/comments/aaa/video/",
+			"media": {"type": "youtube.com", "oembed": {"title": "clip"}}}},
+		{"kind": "t3", "data": {"title": "text", "permalink": "/r/golang/comments/bbb/text/"}}
+	]}}`)
+
+	posts, _ := parseListingPosts(listing, discardLogger(), extractOptions{})
+	if posts[0].Embed == nil || posts[0].Embed.Provider != "youtube.com" || posts[0].Embed.Title != "clip" {
+		t.Errorf("unexpected embed: %+v", posts[0].Embed)
+	}
+	if posts[1].Embed != nil {
+		t.Errorf("expected no embed for a text post, got %+v", posts[1].Embed)
+	}
+}
+
+const galleryPostFixture = `[
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {
+			"title": "Gallery",
+			"is_gallery": true,
+			"permalink": "/r/golang/comments/abc123/gallery/",
+			"gallery_data": {"items": [{"media_id": "zzz"}, {"media_id": "aaa"}, {"media_id": "mmm"}]},
+			"media_metadata": {
+				"aaa": {"status": "valid", "e": "Image", "s": {"u": "https://preview.redd.it/aaa.jpg?a=1&amp;b=2"}},
+				"mmm": {"status": "valid", "e": "Image", "s": {"u": "https://preview.redd.it/mmm.jpg"}},
+				"zzz": {"status": "valid", "e": "Image", "s": {"u": "https://preview.redd.it/zzz.jpg"}}
+			}
+		}}
+	]}},
+	{"kind": "Listing", "data": {"children": []}}
+]`
+
+func TestGalleryImagesFollowGalleryOrder(t *testing.T) {
+	post, err := fixtureExtractor(galleryPostFixture).ExtractRedditPost(context.Background(), testPostURL)
+	if err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	want := []string{
+		"https://preview.redd.it/zzz.jpg",
+		"https://preview.redd.it/aaa.jpg?a=1&b=2",
+		"https://preview.redd.it/mmm.jpg",
+	}
+	if len(post.Images) != len(want) {
+		t.Fatalf("images = %v, want %v", post.Images, want)
+	}
+	for i := range want {
+		if post.Images[i] != want[i] {
+			t.Fatalf("images = %v, want %v", post.Images, want)
+		}
+	}
+}
+
+func TestMaxImages(t *testing.T) {
+	post, err := fixtureExtractor(galleryPostFixture).ExtractRedditPostWithOptions(context.Background(), testPostURL, MaxImages(2))
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if len(post.Images) != 2 || post.Images[0] != "https://preview.redd.it/zzz.jpg" {
+		t.Errorf("images = %v, want the first two in gallery order", post.Images)
+	}
+
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"title": "gallery", "permalink": "/r/golang/comments/aaa/gallery/", "is_gallery": true,
+			"gallery_data": {"items": [{"media_id": "b"}, {"media_id": "a"}]},
+			"media_metadata": {
+				"a": {"status": "valid", "e": "Image", "m": "image/jpg", "s": {"u": "https://i.redd.it/a.jpg"}},
+				"b": {"status": "valid", "e": "Image", "m": "image/png", "s": {"u": "https://i.redd.it/b.jpg"}}
+			}}}
+	]}}`)
+	posts, _ := parseListingPosts(listing, discardLogger(), extractOptions{maxImages: 1})
+	if len(posts[0].ImageURLs) != 1 || posts[0].ImageURLs[0] != "https://i.redd.it/b.jpg" {
+		t.Errorf("image urls = %v, want [https://i.redd.it/b.jpg]", posts[0].ImageURLs)
+	}
+}
+
+func TestGalleryImagesWithoutGalleryDataAreSorted(t *testing.T) {
+	images := galleryImages(nil, map[string]redditMediaItem{
+		"b": {Status: "valid", E: "Image", S: redditImageSource{U: "https://i.redd.it/b.jpg"}},
+		"a": {Status: "valid", E: "Image", S: redditImageSource{U: "https://i.redd.it/a.jpg"}},
+	})
+	if len(images) != 2 || images[0] != "https://i.redd.it/a.jpg" {
+		t.Errorf("images = %v, want sorted by media id", images)
+	}
+}
+
+func TestIsValidImageURL(t *testing.T) {
+	cases := map[string]bool{
+		"https://i.redd.it/a.jpg":          true,
+		"http://preview.redd.it/b.png?x=1": true,
+		"HTTPS://i.redd.it/c.jpg":          true,
+		"":                                 false,
+		"javascript:alert(1)":              false,
+		"data:image/png;base64,AAAA":       false,
+		"//i.redd.it/a.jpg":                false,
+		"/relative/a.jpg":                  false,
+		"https://":                         false,
+		"ftp://files.example.com/a.jpg":    false,
+		"https://i.redd.it/%zz":            false,
+		"  https://i.redd.it/padded.jpg  ": true,
+	}
+	for raw, want := range cases {
+		if got := isValidImageURL(raw); got != want {
+			t.Errorf("isValidImageURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestCollectPostImagesDropsInvalidURLs(t *testing.T) {
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"title": "gallery", "permalink": "/r/golang/comments/aaa/gallery/", "is_gallery": true,
+			"media_metadata": {
+				"a": {"status": "valid", "e": "Image", "m": "image/jpg", "s": {"u": "javascript:alert(1)"}},
+				"b": {"status": "valid", "e": "Image", "m": "image/png", "s": {"u": "https://i.redd.it/b.jpg"}}
+			}}},
+		{"kind": "t3", "data": {"title": "image", "permalink": "/r/golang/comments/bbb/image/", "post_hint": "image",
+			"url": "javascript:alert(1)",
+			"preview": {"images": [{"source": {"url": "not a url"}}, {"source": {"url": "https://preview.redd.it/p.jpg?a=1&amp;b=2"}}]}}}
+	]}}`)
+
+	posts, _ := parseListingPosts(listing, discardLogger(), extractOptions{})
+	if len(posts[0].ImageURLs) != 1 || posts[0].ImageURLs[0] != "https://i.redd.it/b.jpg" {
+		t.Errorf("gallery image urls = %v", posts[0].ImageURLs)
+	}
+	if len(posts[1].ImageURLs) != 1 || posts[1].ImageURLs[0] != "https://preview.redd.it/p.jpg?a=1&b=2" {
+		t.Errorf("preview image urls = %v", posts[1].ImageURLs)
+	}
+}
+
+func TestRewritePreviewURL(t *testing.T) {
+	cases := map[string]string{
+		"https://preview.redd.it/abc123.jpg?width=640&s=sig": "https://i.redd.it/abc123.jpg",
+		"https://PREVIEW.redd.it/abc123.png":                 "https://i.redd.it/abc123.png",
+		// Not derivable: slugged names, other hosts and odd paths stay.
+		"https://preview.redd.it/my-photo-v0-abc123.jpg?s=sig": "https://preview.redd.it/my-photo-v0-abc123.jpg?s=sig",
+		"https://external-preview.redd.it/abc123.jpg?s=sig":    "https://external-preview.redd.it/abc123.jpg?s=sig",
+		"https://preview.redd.it/dir/abc123.jpg":               "https://preview.redd.it/dir/abc123.jpg",
+		"https://preview.redd.it/abc123":                       "https://preview.redd.it/abc123",
+		"https://i.redd.it/abc123.jpg":                         "https://i.redd.it/abc123.jpg",
+	}
+	for raw, want := range cases {
+		if got := rewritePreviewURL(raw); got != want {
+			t.Errorf("rewritePreviewURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestRewritePreviewImages(t *testing.T) {
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"title": "image", "permalink": "/r/golang/comments/aaa/image/",
+			"preview": {"images": [
+				{"source": {"url": "https://preview.redd.it/abc123.jpg?width=640&amp;s=sig"}},
+				{"source": {"url": "https://external-preview.redd.it/xyz.jpg?s=sig"}}
+			]}}}
+	]}}`)
+	posts, _ := parseListingPosts(listing, discardLogger(), extractOptions{rewritePreviews: true})
+	want := []string{"https://i.redd.it/abc123.jpg", "https://external-preview.redd.it/xyz.jpg?s=sig"}
+	if len(posts[0].ImageURLs) != 2 || posts[0].ImageURLs[0] != want[0] || posts[0].ImageURLs[1] != want[1] {
+		t.Errorf("image urls = %v, want %v", posts[0].ImageURLs, want)
+	}
+
+	post, err := fixtureExtractor(galleryPostFixture).ExtractRedditPostWithOptions(context.Background(), testPostURL, RewritePreviewImages())
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if post.Images[0] != "https://i.redd.it/zzz.jpg" {
+		t.Errorf("images = %v, want preview urls rewritten", post.Images)
+	}
+}
+
+func TestRewritePreviewImagesHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, `<html><body><h1>From HTML</h1>
+			<img src="https://preview.redd.it/abc123.jpg?width=640&s=sig">
+			<img src="https://preview.redd.it/slug-v0-def456.jpg?s=sig"></body></html>`)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	e := mustNewExtractor(WithAllowedHosts([]string{u.Hostname()}))
+
+	post, err := e.ExtractRedditPostWithOptions(context.Background(), server.URL+"/r/golang/comments/abc123/x/", HTMLOnly(), RewritePreviewImages())
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	want := []string{"https://i.redd.it/abc123.jpg", "https://preview.redd.it/slug-v0-def456.jpg?s=sig"}
+	if len(post.Images) != 2 || post.Images[0] != want[0] || post.Images[1] != want[1] {
+		t.Errorf("images = %v, want %v", post.Images, want)
+	}
+}
+
+const mixedResolutionListing = `{"kind": "Listing", "data": {"children": [
+	{"kind": "t3", "data": {"id": "big", "title": "big", "permalink": "/r/wallpapers/comments/big/big/", "post_hint": "image",
+		"url": "https://i.redd.it/big.jpg",
+		"preview": {"images": [{"source": {"url": "https://preview.redd.it/big.jpg", "width": 3840, "height": 2160}}]}}},
+	{"kind": "t3", "data": {"id": "small", "title": "small", "permalink": "/r/wallpapers/comments/small/small/", "post_hint": "image",
+		"url": "https://i.redd.it/small.jpg",
+		"preview": {"images": [{"source": {"url": "https://preview.redd.it/small.jpg", "width": 640, "height": 480}}]}}},
+	{"kind": "t3", "data": {"id": "gal", "title": "gallery", "permalink": "/r/wallpapers/comments/gal/gallery/", "is_gallery": true,
+		"gallery_data": {"items": [{"media_id": "a"}, {"media_id": "b"}]},
+		"media_metadata": {
+			"a": {"status": "valid", "e": "Image", "m": "image/jpg", "s": {"u": "https://preview.redd.it/a.jpg", "x": 800, "y": 600}},
+			"b": {"status": "valid", "e": "Image", "m": "image/png", "s": {"u": "https://preview.redd.it/b.jpg", "x": 2560, "y": 1440}}
+		}}},
+	{"kind": "t3", "data": {"id": "txt", "title": "text", "permalink": "/r/wallpapers/comments/txt/text/", "is_self": true}}
+]}}`
+
+func TestImageInfo(t *testing.T) {
+	posts, _ := parseListingPosts(decodeListing(t, mixedResolutionListing), discardLogger(), extractOptions{})
+	if want := (ImageInfo{URL: "https://i.redd.it/big.jpg", Width: 3840, Height: 2160}); len(posts[0].Images) != 1 || posts[0].Images[0] != want {
+		t.Errorf("images = %+v, want %+v", posts[0].Images, want)
+	}
+	wantGallery := []ImageInfo{
+		{URL: "https://preview.redd.it/a.jpg", Width: 800, Height: 600, Type: "image/jpg"},
+		{URL: "https://preview.redd.it/b.jpg", Width: 2560, Height: 1440, Type: "image/png"},
+	}
+	if len(posts[2].Images) != 2 || posts[2].Images[0] != wantGallery[0] || posts[2].Images[1] != wantGallery[1] {
+		t.Errorf("gallery images = %+v, want %+v", posts[2].Images, wantGallery)
+	}
+	if len(posts[2].ImageURLs) != 2 || posts[2].ImageURLs[0] != wantGallery[0].URL || posts[2].ImageURLs[1] != wantGallery[1].URL {
+		t.Errorf("gallery image URLs = %v, want the URLs of Images", posts[2].ImageURLs)
+	}
+	if posts[3].Images != nil {
+		t.Errorf("text post images = %+v, want none", posts[3].Images)
+	}
+}
+
+func TestMinImageSize(t *testing.T) {
+	listing := decodeListing(t, mixedResolutionListing)
+
+	posts, filtered := parseListingPosts(listing, discardLogger(), applyExtractOptions([]ExtractOption{MinImageSize(1920, 1080, false)}))
+	if len(posts) != 4 || filtered != 0 {
+		t.Fatalf("posts = %d, filtered = %d, want all 4 kept", len(posts), filtered)
+	}
+	if len(posts[1].ImageURLs) != 0 {
+		t.Errorf("small image kept: %v", posts[1].ImageURLs)
+	}
+	if len(posts[2].ImageURLs) != 1 || posts[2].ImageURLs[0] != "https://preview.redd.it/b.jpg" {
+		t.Errorf("gallery images = %v, want only the large one", posts[2].ImageURLs)
+	}
+
+	posts, filtered = parseListingPosts(listing, discardLogger(), applyExtractOptions([]ExtractOption{MinImageSize(1920, 1080, true)}))
+	var ids []string
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	if len(ids) != 2 || ids[0] != "big" || ids[1] != "gal" || filtered != 2 {
+		t.Errorf("kept %v with %d filtered, want [big gal] and 2", ids, filtered)
+	}
+}
+
+func TestPreferDurableImages(t *testing.T) {
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"title": "gallery", "permalink": "/r/golang/comments/aaa/gallery/", "is_gallery": true,
+			"gallery_data": {"items": [{"media_id": "abc123"}, {"media_id": "def456"}, {"media_id": "ghi789"}]},
+			"media_metadata": {
+				"abc123": {"status": "valid", "e": "Image", "m": "image/jpg", "s": {"u": "https://preview.redd.it/abc123.jpg?width=1080&amp;s=sig", "x": 1080, "y": 720}},
+				"def456": {"status": "valid", "e": "Image", "m": "image/png", "s": {"u": "https://preview.redd.it/def456.png?width=640&amp;s=sig"}},
+				"ghi789": {"status": "valid", "e": "Image", "m": "", "s": {"u": "https://preview.redd.it/ghi789.jpg?s=sig"}}
+			},
+			"preview": {"images": [{"source": {"url": "https://preview.redd.it/abc123.jpg?s=sig"}}]}}}
+	]}}`)
+
+	posts, _ := parseListingPosts(listing, discardLogger(), extractOptions{})
+	if got := posts[0].ImageURLs[0]; got != "https://preview.redd.it/abc123.jpg?width=1080&s=sig" {
+		t.Errorf("default image url = %q, want the signed one", got)
+	}
+
+	posts, _ = parseListingPosts(listing, discardLogger(), extractOptions{preferDurableImages: true})
+	want := []string{
+		"https://i.redd.it/abc123.jpg",
+		"https://i.redd.it/def456.png",
+		// No MIME type, so no durable URL to prefer.
+		"https://preview.redd.it/ghi789.jpg?s=sig",
+	}
+	if len(posts[0].ImageURLs) != len(want) {
+		t.Fatalf("image urls = %v, want %v", posts[0].ImageURLs, want)
+	}
+	for i := range want {
+		if posts[0].ImageURLs[i] != want[i] {
+			t.Errorf("image url %d = %q, want %q", i, posts[0].ImageURLs[i], want[i])
+		}
+	}
+	if img := posts[0].Images[0]; img.Width != 1080 || img.Height != 720 || img.Type != "image/jpg" {
+		t.Errorf("durable image info = %+v, want the metadata dimensions kept", img)
+	}
+}
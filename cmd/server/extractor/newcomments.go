@@ -0,0 +1,76 @@
+package extractor
+
+import (
+	"context"
+	"sort"
+)
+
+// NewComments holds the comments of a thread posted since a marker, as
+// returned by ExtractNewComments.
+type NewComments struct {
+	// Comments are the new comments at any depth, oldest first. Replies
+	// are not nested: each new reply is listed on its own.
+	Comments []Comment `json:"comments"`
+	// Latest is the CreatedUTC of the newest comment seen, or the marker
+	// itself when there was none; pass it as since to the next poll.
+	Latest int64 `json:"latest"`
+	// MayHaveMissed is set when even the oldest comment returned is newer
+	// than the marker. Reddit only returns part of a large thread, so
+	// comments posted between the marker and that one may not have been
+	// seen, typically because the polls are too far apart.
+	MayHaveMissed bool `json:"may_have_missed,omitempty"`
+}
+
+// ExtractNewComments fetches the comments posted since a marker using the
+// default Extractor.
+func ExtractNewComments(ctx context.Context, redditURL string, since int64, opts ...ExtractOption) (*NewComments, error) {
+	return defaultExtractor.ExtractNewComments(ctx, redditURL, since, opts...)
+}
+
+// ExtractNewComments returns the comments of the post at redditURL created
+// after since, in Unix seconds, for polling a live thread: each call passes
+// the Latest of the previous one. Comments are fetched sorted by new, which
+// replaces any sort chosen by opts; the other comment options of opts apply
+// before the filtering. since <= 0 returns every comment fetched.
+func (e *Extractor) ExtractNewComments(ctx context.Context, redditURL string, since int64, opts ...ExtractOption) (*NewComments, error) {
+	opts = append(opts, func(o *extractOptions) {
+		o.commentSort = "new"
+	})
+	comments, err := e.ExtractRedditComments(ctx, redditURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newCommentsSince(comments, since), nil
+}
+
+// newCommentsSince lists the comments of the tree created after since.
+// Comments without a creation time are never new.
+func newCommentsSince(tree []Comment, since int64) *NewComments {
+	result := &NewComments{Comments: []Comment{}, Latest: since}
+	var oldest int64
+	var walk func([]Comment)
+	walk = func(comments []Comment) {
+		for _, c := range comments {
+			walk(c.Replies)
+			if c.CreatedUTC <= 0 {
+				continue
+			}
+			if oldest == 0 || c.CreatedUTC < oldest {
+				oldest = c.CreatedUTC
+			}
+			if c.CreatedUTC <= since {
+				continue
+			}
+			c.Replies = nil
+			result.Comments = append(result.Comments, c)
+			result.Latest = max(result.Latest, c.CreatedUTC)
+		}
+	}
+	walk(tree)
+
+	sort.SliceStable(result.Comments, func(i, j int) bool {
+		return result.Comments[i].CreatedUTC < result.Comments[j].CreatedUTC
+	})
+	result.MayHaveMissed = since > 0 && oldest > since
+	return result
+}
@@ -0,0 +1,66 @@
+package extractor
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const timestampedThreadFixture = `[
+	{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Live thread"}}]}},
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t1", "data": {"id": "d", "body": "newest", "created_utc": 1700000400.0, "replies": ""}},
+		{"kind": "t1", "data": {"id": "a", "body": "old", "created_utc": 1700000100.0, "replies": {
+			"kind": "Listing", "data": {"children": [
+				{"kind": "t1", "data": {"id": "c", "body": "new reply", "created_utc": 1700000300.0, "replies": ""}},
+				{"kind": "t1", "data": {"id": "b", "body": "old reply", "created_utc": 1700000200.0, "replies": ""}}
+			]}
+		}}}
+	]}}
+]`
+
+func TestExtractNewComments(t *testing.T) {
+	var query string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		query = req.URL.RawQuery
+		return cannedResponse(req, http.StatusOK, timestampedThreadFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+	ids := func(comments []Comment) []string {
+		var ids []string
+		for _, c := range comments {
+			if c.Replies != nil {
+				t.Errorf("comment %s kept its replies", c.ID)
+			}
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		name   string
+		since  int64
+		want   []string
+		latest int64
+		missed bool
+	}{
+		{"first poll", 0, []string{"a", "b", "c", "d"}, 1700000400, false},
+		{"since a reply", 1700000200, []string{"c", "d"}, 1700000400, false},
+		{"nothing new", 1700000400, nil, 1700000400, false},
+		{"marker before the oldest", 1700000000, []string{"a", "b", "c", "d"}, 1700000400, true},
+	}
+	for _, tc := range cases {
+		got, err := e.ExtractNewComments(context.Background(), testPostURL, tc.since)
+		if err != nil {
+			t.Fatalf("%s: ExtractNewComments failed: %v", tc.name, err)
+		}
+		if query != "sort=new" {
+			t.Errorf("%s: query = %q, want sort=new", tc.name, query)
+		}
+		if !reflect.DeepEqual(ids(got.Comments), tc.want) || got.Latest != tc.latest || got.MayHaveMissed != tc.missed {
+			t.Errorf("%s: got %v, latest %d, missed %v; want %v, latest %d, missed %v",
+				tc.name, ids(got.Comments), got.Latest, got.MayHaveMissed, tc.want, tc.latest, tc.missed)
+		}
+	}
+}
@@ -0,0 +1,609 @@
+package extractor
+
+import (
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// Option configures an Extractor.
+type Option func(*Extractor)
+
+// WithHTTPClient sets the client used verbatim for all Reddit API requests,
+// overriding the built-in timeout and transport settings. The deadline of
+// the context passed to each extraction still applies on top of the client's
+// own timeout. WithHTTPClient and WithTransport are mutually exclusive: the
+// last one given wins and a warning is logged.
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *Extractor) {
+		if e.transport != nil {
+			log.Printf("[extractor] WithHTTPClient overrides an earlier WithTransport")
+			e.transport = nil
+		}
+		e.httpClient = client
+	}
+}
+
+// WithTransport sets the transport of the built-in client, giving full
+// control over connection pooling, TLS and HTTP/2 while keeping the default
+// request timeout. See WithHTTPClient for how the two interact.
+func WithTransport(transport *http.Transport) Option {
+	return func(e *Extractor) {
+		if e.httpClient != nil {
+			log.Printf("[extractor] WithTransport overrides an earlier WithHTTPClient")
+			e.httpClient = nil
+		}
+		e.transport = transport
+	}
+}
+
+// WithTracer sets the Tracer used to create spans around extractions and
+// each outbound Reddit request. By default no spans are recorded.
+func WithTracer(tracer Tracer) Option {
+	return func(e *Extractor) {
+		e.tracer = tracer
+	}
+}
+
+// WithClock sets the Clock used for time-dependent logic. It defaults to the
+// system wall clock and exists mainly so tests can inject a fake one.
+func WithClock(clock Clock) Option {
+	return func(e *Extractor) {
+		e.clock = clock
+	}
+}
+
+// WithMaxConcurrency caps the number of Reddit requests in flight across all
+// operations of the Extractor at n. Requests over the cap wait for a free
+// slot, giving up if their context is done. n <= 0 means no limit, which is
+// the default.
+func WithMaxConcurrency(n int) Option {
+	return func(e *Extractor) {
+		if n <= 0 {
+			e.sem = nil
+			return
+		}
+		e.sem = semaphore.NewWeighted(int64(n))
+	}
+}
+
+// WithMinInterval spaces outbound Reddit requests so consecutive ones start
+// at least d apart, regardless of how many goroutines use the Extractor. A
+// request waiting for its turn gives up if its context is done. Combined with
+// WithMaxConcurrency, a request first takes a concurrency slot and then waits
+// for its turn, so the interval bounds the request rate while the cap bounds
+// how many slow responses can pile up. d <= 0 disables the gate, which is the
+// default.
+func WithMinInterval(d time.Duration) Option {
+	return func(e *Extractor) {
+		e.minInterval = d
+	}
+}
+
+// WithGovernor makes the Extractor's requests to the Reddit API also wait
+// for their turn at g, which may be shared with other Extractors to bound
+// their combined request rate. It applies after WithMinInterval, which only
+// spaces this Extractor's own requests. A nil g disables it, which is the
+// default.
+func WithGovernor(g *Governor) Option {
+	return func(e *Extractor) {
+		e.governor = g
+	}
+}
+
+// WithCircuitBreaker stops sending requests to the Reddit API once it keeps
+// failing: after failures consecutive requests end in a transport error,
+// timeout, 429 or 5xx within window, every request fails fast with a
+// CircuitOpenError for cooldown. The next request after that is let through
+// as a probe, and closes the circuit again if it succeeds. A request counts
+// once however often it was retried, and posts do not fall back to HTML
+// scraping while the circuit is open. failures <= 0 disables the breaker,
+// which is the default. NewExtractor fails if failures > 1 and window <= 0,
+// since no two failures would then count together.
+func WithCircuitBreaker(failures int, window, cooldown time.Duration) Option {
+	return func(e *Extractor) {
+		e.breakerThreshold = failures
+		e.breakerWindow = window
+		e.breakerCooldown = cooldown
+	}
+}
+
+// WithMaxRedirects caps the redirects a single request follows at n, both
+// for API requests and HTML scraping, and stops any request redirected back
+// to a URL it already visited twice; a single return to the same URL, as in
+// Reddit's cookie bounce, is allowed. Either case fails the request with a
+// RedirectError. n = 0 follows no redirects; n < 0 restores the net/http
+// default of ten without loop detection, which is the default.
+func WithMaxRedirects(n int) Option {
+	return func(e *Extractor) {
+		e.maxRedirects = n
+		e.limitRedirects = n >= 0
+	}
+}
+
+// WithAllowedHosts replaces the set of hosts accepted by the post and
+// subreddit URL validators. Hosts are matched exactly and case-insensitively,
+// ignoring any port. The default covers reddit.com, www, old, new and m.
+func WithAllowedHosts(hosts []string) Option {
+	return func(e *Extractor) {
+		e.allowedHosts = make(map[string]struct{}, len(hosts))
+		for _, host := range hosts {
+			e.allowedHosts[strings.ToLower(strings.TrimSpace(host))] = struct{}{}
+		}
+	}
+}
+
+// WithPostFilter drops listing posts for which keep returns false. It runs
+// after the built-in filters (removed posts, ImagesOnly, SelfOnly) and
+// before SkipSeen, so filtered posts are not marked as seen; dropped posts
+// count as filtered.
+func WithPostFilter(keep func(SubredditPost) bool) Option {
+	return func(e *Extractor) {
+		e.postFilter = keep
+	}
+}
+
+// WithAcceptLanguage sends lang as the Accept-Language header on API requests
+// and on the HTML fallback, since Reddit localizes some content by it. An
+// empty lang, the default, sends no header.
+func WithAcceptLanguage(lang string) Option {
+	return func(e *Extractor) {
+		e.acceptLanguage = strings.TrimSpace(lang)
+	}
+}
+
+// WithEmptyListingRetry makes ExtractSubredditPosts refetch a listing that
+// came back with no posts at all, up to retries times and delay apart, since
+// Reddit occasionally answers an active subreddit with an empty page.
+// Unavailable subreddits are not retried, and a wait gives up if the context
+// is done. retries <= 0, the default, disables it.
+func WithEmptyListingRetry(retries int, delay time.Duration) Option {
+	return func(e *Extractor) {
+		e.emptyListingRetries = retries
+		e.emptyListingDelay = delay
+	}
+}
+
+// WithSubredditLimits sets the listing page size used when a caller passes
+// limit 0, and the largest limit accepted; ExtractSubredditPostsN also pages
+// in chunks of at most max. max is capped at Reddit's own maximum of 100,
+// and def at max. Values <= 0 keep the defaults of 20 and 100.
+func WithSubredditLimits(def, max int) Option {
+	return func(e *Extractor) {
+		if def > 0 {
+			e.defaultLimit = def
+		}
+		if max > maxSubredditLimit {
+			max = maxSubredditLimit
+		}
+		if max > 0 {
+			e.maxLimit = max
+		}
+	}
+}
+
+// WithDefaultSort sets the listing sort ExtractSubredditPosts uses when the
+// caller passes none, "hot" by default; an empty sort keeps it. NewExtractor
+// fails if sort is not one of hot, new, top or rising.
+func WithDefaultSort(sort string) Option {
+	return func(e *Extractor) {
+		if strings.TrimSpace(sort) != "" {
+			e.defaultSort = sort
+		}
+	}
+}
+
+// WithStrictValidation makes ExtractSubredditPosts reject an empty sort or a
+// zero limit with a ValidationError instead of applying the defaults, for
+// callers that want every request to be explicit. It is off by default.
+func WithStrictValidation(strict bool) Option {
+	return func(e *Extractor) {
+		e.strictValidation = strict
+	}
+}
+
+// WithFallbackToRSS makes ExtractSubredditPosts fetch the subreddit's RSS
+// feed, as ExtractSubredditPostsRSS does, when the JSON API answers with a
+// block page or a 403. The response's Source tells which of the two was
+// used; posts from the feed lack most fields, see ExtractSubredditPostsRSS.
+// ImagesOnly and SelfOnly cannot be told from the feed, so with either set
+// it yields no posts. A subreddit the feed reports as unavailable yields an
+// empty listing. If the feed fails too, a 403 yields the empty listing it
+// does without the fallback, and a block page its BlockedError. It is off
+// by default.
+func WithFallbackToRSS(fallback bool) Option {
+	return func(e *Extractor) {
+		e.fallbackToRSS = fallback
+	}
+}
+
+// WithRetry retries Reddit requests that fail with a transport error, 429 or
+// a 502, 503 or 504 response up to retries times, backing off as set by
+// WithBackoff and WithJitter. A retry whose wait would outlast the context
+// deadline is not attempted and the last failure is returned right away.
+// retries <= 0, the default, disables retrying.
+func WithRetry(retries int) Option {
+	return func(e *Extractor) {
+		e.retries = retries
+	}
+}
+
+// WithRetryBudget caps the total time a request may spend on retries,
+// backoff included, at d, independently of the number of retries allowed by
+// WithRetry. d <= 0, the default, leaves only the context deadline.
+func WithRetryBudget(d time.Duration) Option {
+	return func(e *Extractor) {
+		e.retryBudget = d
+	}
+}
+
+// WithBackoff sets the backoff of retried requests: base before the first
+// retry, multiplied by multiplier for each further one, up to 30s. The wait
+// actually used is randomized by WithJitter. Values that would not back off,
+// base <= 0 or multiplier < 1, keep the defaults of 500ms and 2.
+func WithBackoff(base time.Duration, multiplier float64) Option {
+	return func(e *Extractor) {
+		if base > 0 {
+			e.retryBaseDelay = base
+		}
+		if multiplier >= 1 {
+			e.retryMultiplier = multiplier
+		}
+	}
+}
+
+// WithJitter sets how retry backoffs are randomized. It defaults to
+// JitterFull.
+func WithJitter(mode JitterMode) Option {
+	return func(e *Extractor) {
+		e.retryJitter = mode
+	}
+}
+
+// WithRandSource sets the source of randomness used for the retry jitter,
+// so tests can supply a seeded one and assert exact delays. It defaults to
+// the securely seeded global source of math/rand/v2.
+func WithRandSource(src rand.Source) Option {
+	return func(e *Extractor) {
+		r := rand.New(src)
+		var mu sync.Mutex
+		e.random = func() float64 {
+			mu.Lock()
+			defer mu.Unlock()
+			return r.Float64()
+		}
+	}
+}
+
+// ExtractOption adjusts a single extraction call, as opposed to Option,
+// which configures an Extractor for all of them.
+type ExtractOption func(*extractOptions)
+
+type extractOptions struct {
+	seen       SeenSet
+	source     postSource
+	maxImages  int
+	imagesOnly bool
+	selfOnly   bool
+
+	commentBody    commentBodyOptions
+	commentAuthors commentAuthorFilter
+
+	commentSort       string
+	topComments       int
+	topCommentReplies bool
+
+	dropStickied      bool
+	dropAutoModerator bool
+
+	viewNSFW bool
+
+	preferHTMLWhenIncomplete bool
+
+	skipComments bool
+
+	fieldCoverage bool
+
+	rewritePreviews     bool
+	preferDurableImages bool
+
+	minImageWidth       int
+	minImageHeight      int
+	dropSmallImagePosts bool
+
+	stopOnError bool
+
+	previewRunes int
+}
+
+// postSource selects which extraction paths a post extraction may use.
+type postSource int
+
+const (
+	sourceAuto postSource = iota // API first, HTML on failure
+	sourceAPI
+	sourceHTML
+)
+
+// finishImages applies the image options to an extracted image list.
+func (o extractOptions) finishImages(images []string) []string {
+	if o.rewritePreviews {
+		images = rewritePreviewURLs(images)
+	}
+	return limitImages(images, o.maxImages)
+}
+
+// finishImageInfo is finishImages for listing images, which also carry
+// their dimensions and so can be filtered by MinImageSize.
+func (o extractOptions) finishImageInfo(images []ImageInfo) []ImageInfo {
+	if o.minImageWidth > 0 || o.minImageHeight > 0 {
+		images = filterImageSize(images, o.minImageWidth, o.minImageHeight)
+	}
+	if o.rewritePreviews {
+		for i := range images {
+			images[i].URL = rewritePreviewURL(images[i].URL)
+		}
+	}
+	if o.maxImages > 0 && len(images) > o.maxImages {
+		images = images[:o.maxImages]
+	}
+	return images
+}
+
+// finishComments applies the comment options to an extracted comment tree.
+func (o extractOptions) finishComments(comments []Comment) []Comment {
+	if !o.commentAuthors.isZero() {
+		comments = o.commentAuthors.apply(comments)
+	}
+	if o.dropStickied || o.dropAutoModerator {
+		comments = dropTopLevelComments(comments, func(c Comment) bool {
+			return (o.dropStickied && c.Stickied) ||
+				(o.dropAutoModerator && strings.EqualFold(c.Author, automoderatorAuthor))
+		})
+	}
+	if o.topComments > 0 {
+		comments = topComments(comments, o.topComments, o.topCommentReplies)
+	}
+	if !o.commentBody.isZero() {
+		cleanCommentBodies(comments, o.commentBody)
+	}
+	return comments
+}
+
+func applyExtractOptions(opts []ExtractOption) extractOptions {
+	o := extractOptions{previewRunes: defaultPreviewRunes}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// SkipSeen drops listing posts whose ID is already in set and adds the IDs
+// of the posts returned, so repeated calls only yield posts not returned
+// before. Skipped posts are counted in SubredditListResponse.SeenSkipped.
+func SkipSeen(set SeenSet) ExtractOption {
+	return func(o *extractOptions) {
+		o.seen = set
+	}
+}
+
+// APIOnly restricts a post extraction to the JSON API: when it fails, the
+// error is returned instead of falling back to HTML scraping. It replaces an
+// earlier HTMLOnly.
+func APIOnly() ExtractOption {
+	return func(o *extractOptions) {
+		o.source = sourceAPI
+	}
+}
+
+// HTMLOnly skips the JSON API and scrapes the post page directly, which
+// helps with posts the API cannot reach. It replaces an earlier APIOnly.
+func HTMLOnly() ExtractOption {
+	return func(o *extractOptions) {
+		o.source = sourceHTML
+	}
+}
+
+// MaxImages keeps at most n image URLs per post, in display order, for both
+// posts and listing entries. n <= 0 keeps them all.
+func MaxImages(n int) ExtractOption {
+	return func(o *extractOptions) {
+		o.maxImages = n
+	}
+}
+
+// ImagesOnly drops listing posts without any image URL. They count towards
+// the removed-post total in the extraction log. It cannot be combined with
+// SelfOnly.
+func ImagesOnly() ExtractOption {
+	return func(o *extractOptions) {
+		o.imagesOnly = true
+	}
+}
+
+// SelfOnly keeps only self (text) posts in a listing. Like ImagesOnly, which
+// it cannot be combined with, dropped posts count as filtered.
+func SelfOnly() ExtractOption {
+	return func(o *extractOptions) {
+		o.selfOnly = true
+	}
+}
+
+// UnescapeComments decodes the HTML entities (such as &gt; and &amp;) that
+// Reddit leaves in comment bodies.
+func UnescapeComments() ExtractOption {
+	return func(o *extractOptions) {
+		o.commentBody.unescape = true
+	}
+}
+
+// PlainTextComments strips markdown from comment bodies, leaving plain
+// text. It also unescapes them as UnescapeComments does.
+func PlainTextComments() ExtractOption {
+	return func(o *extractOptions) {
+		o.commentBody.plainText = true
+	}
+}
+
+// TruncateComments shortens comment bodies to at most n runes, ending cut
+// bodies with an ellipsis. It applies after any other comment cleanup;
+// n <= 0 disables it.
+func TruncateComments(n int) ExtractOption {
+	return func(o *extractOptions) {
+		o.commentBody.maxRunes = n
+	}
+}
+
+// TopComments fetches comments sorted by "top" and keeps only the n
+// highest-scored top-level ones, in descending score order. With
+// withReplies set their direct replies are kept too; deeper replies are
+// always dropped. n <= 0 disables it.
+func TopComments(n int, withReplies bool) ExtractOption {
+	return func(o *extractOptions) {
+		o.topComments = n
+		o.topCommentReplies = withReplies
+		if n > 0 {
+			o.commentSort = "top"
+		}
+	}
+}
+
+// DropStickiedComments removes stickied top-level comments, usually
+// moderator notes pinned to the top of the thread, with their replies.
+func DropStickiedComments() ExtractOption {
+	return func(o *extractOptions) {
+		o.dropStickied = true
+	}
+}
+
+// DropAutoModeratorComments removes top-level comments by AutoModerator,
+// with their replies.
+func DropAutoModeratorComments() ExtractOption {
+	return func(o *extractOptions) {
+		o.dropAutoModerator = true
+	}
+}
+
+// ExcludeCommentAuthors drops comments by any of authors, at every depth,
+// together with their replies unless KeepOrphanedReplies is given. Names
+// match case-insensitively, and repeated calls add to the set.
+func ExcludeCommentAuthors(authors ...string) ExtractOption {
+	return func(o *extractOptions) {
+		o.commentAuthors.deny = addAuthors(o.commentAuthors.deny, authors)
+	}
+}
+
+// OnlyCommentAuthors keeps only comments by one of authors, at every depth,
+// dropping the others as ExcludeCommentAuthors does. Names match
+// case-insensitively, and repeated calls add to the set.
+func OnlyCommentAuthors(authors ...string) ExtractOption {
+	return func(o *extractOptions) {
+		o.commentAuthors.allow = addAuthors(o.commentAuthors.allow, authors)
+	}
+}
+
+// KeepOrphanedReplies makes ExcludeCommentAuthors and OnlyCommentAuthors
+// keep the replies of a dropped comment, moved up to its place in the tree,
+// instead of dropping them with it.
+func KeepOrphanedReplies() ExtractOption {
+	return func(o *extractOptions) {
+		o.commentAuthors.keepOrphans = true
+	}
+}
+
+// ViewNSFW confirms Reddit's over-18 interstitial for age-gated posts by
+// retrying with the over18 cookie. Without it such posts fail with an
+// AgeGatedError rather than falling back to HTML, which shows the same
+// interstitial.
+func ViewNSFW() ExtractOption {
+	return func(o *extractOptions) {
+		o.viewNSFW = true
+	}
+}
+
+// PreferHTMLWhenIncomplete also scrapes the HTML page when the API returns a
+// post missing its author, time or content, and uses it to fill the gaps
+// as described for mergeRedditPosts. Fields the API filled are never
+// overwritten, and a failed scrape leaves the API result as is.
+func PreferHTMLWhenIncomplete() ExtractOption {
+	return func(o *extractOptions) {
+		o.preferHTMLWhenIncomplete = true
+	}
+}
+
+// IncludeComments controls whether the comment tree of a post is parsed.
+// It defaults to true; callers that only need the post metadata can turn it
+// off to skip parsing the comments, which dominates the cost on large
+// threads. The comments are still part of the API response Reddit sends.
+func IncludeComments(include bool) ExtractOption {
+	return func(o *extractOptions) {
+		o.skipComments = !include
+	}
+}
+
+// ReportFieldCoverage fills SubredditListResponse.FieldCoverage, a count of
+// how many listing posts had each key field populated. It is a diagnostic
+// for noticing Reddit schema changes and is off by default.
+func ReportFieldCoverage() ExtractOption {
+	return func(o *extractOptions) {
+		o.fieldCoverage = true
+	}
+}
+
+// RewritePreviewImages replaces preview.redd.it image URLs, which are signed
+// and expire, with the stable i.redd.it URL of the same image wherever that
+// can be derived from the URL; other URLs are kept as they are.
+func RewritePreviewImages() ExtractOption {
+	return func(o *extractOptions) {
+		o.rewritePreviews = true
+	}
+}
+
+// PreferDurableImages lists gallery images in listings by their i.redd.it
+// URL, which is unsigned and does not expire, instead of the signed
+// preview.redd.it URL Reddit puts in media_metadata, wherever both exist.
+// That suits archiving, but the durable URL serves the file as uploaded
+// rather than the rendition Reddit shows, so in some cases it is lower
+// quality, much larger or in a format browsers handle less well. Image
+// posts already use their i.redd.it URL without this option.
+func PreferDurableImages() ExtractOption {
+	return func(o *extractOptions) {
+		o.preferDurableImages = true
+	}
+}
+
+// MinImageSize drops listing images smaller than width by height, and those
+// whose size Reddit does not report. With dropPosts set, posts left without
+// any image are dropped as well and counted as filtered. Zero disables a
+// bound; it does not apply to single-post extraction, whose images carry no
+// dimensions.
+func MinImageSize(width, height int, dropPosts bool) ExtractOption {
+	return func(o *extractOptions) {
+		o.minImageWidth = width
+		o.minImageHeight = height
+		o.dropSmallImagePosts = dropPosts && (width > 0 || height > 0)
+	}
+}
+
+// StopOnError makes a batch extraction give up on its first failing URL,
+// cancelling the rest instead of collecting every URL's own result, which is
+// the default.
+func StopOnError() ExtractOption {
+	return func(o *extractOptions) {
+		o.stopOnError = true
+	}
+}
+
+// ContentPreview sets the length, in runes, of SubredditPost.ContentPreview,
+// the start of each self post's text; cut previews end with an ellipsis. It
+// defaults to 200, and n <= 0 omits the previews.
+func ContentPreview(n int) ExtractOption {
+	return func(o *extractOptions) {
+		o.previewRunes = n
+	}
+}
@@ -0,0 +1,50 @@
+package extractor
+
+import "time"
+
+// Poll holds the options and votes of a poll post.
+type Poll struct {
+	Options       []PollOption `json:"options"`
+	TotalVotes    int          `json:"total_votes"`
+	VotingEndsAt  string       `json:"voting_ends_at,omitempty"`
+	UserSelection string       `json:"user_selection,omitempty"`
+}
+
+// PollOption is a single choice of a Poll. Votes stays zero while Reddit
+// hides per-option counts, which it does until voting ends.
+type PollOption struct {
+	Text  string `json:"text"`
+	Votes int    `json:"votes"`
+}
+
+// redditPollData is the poll_data object of a poll post.
+type redditPollData struct {
+	Options []struct {
+		ID        string `json:"id"`
+		Text      string `json:"text"`
+		VoteCount int    `json:"vote_count"`
+	} `json:"options"`
+	TotalVoteCount     int     `json:"total_vote_count"`
+	VotingEndTimestamp float64 `json:"voting_end_timestamp"`
+	UserSelection      string  `json:"user_selection"`
+}
+
+// buildPoll converts poll_data into a Poll, returning nil for non-poll posts.
+func buildPoll(data *redditPollData) *Poll {
+	if data == nil {
+		return nil
+	}
+	poll := &Poll{
+		Options:       make([]PollOption, 0, len(data.Options)),
+		TotalVotes:    data.TotalVoteCount,
+		UserSelection: data.UserSelection,
+	}
+	for _, opt := range data.Options {
+		poll.Options = append(poll.Options, PollOption{Text: opt.Text, Votes: opt.VoteCount})
+	}
+	if data.VotingEndTimestamp > 0 {
+		// Unlike created_utc, voting_end_timestamp is in milliseconds.
+		poll.VotingEndsAt = time.UnixMilli(int64(data.VotingEndTimestamp)).Format(timeLayout)
+	}
+	return poll
+}
@@ -0,0 +1,58 @@
+package extractor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const pollPostFixture = `[
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {
+			"title": "Which editor?",
+			"poll_data": {
+				"options": [
+					{"id": "1", "text": "vim", "vote_count": 12},
+					{"id": "2", "text": "emacs", "vote_count": 8}
+				],
+				"total_vote_count": 20,
+				"voting_end_timestamp": 1700000000000,
+				"user_selection": null
+			}
+		}}
+	]}},
+	{"kind": "Listing", "data": {"children": []}}
+]`
+
+func TestExtractRedditPostPoll(t *testing.T) {
+	post, err := fixtureExtractor(pollPostFixture).extractRedditPostFromAPI(context.Background(), testPostURL, extractOptions{})
+	if err != nil {
+		t.Fatalf("extractRedditPostFromAPI failed: %v", err)
+	}
+	poll := post.Poll
+	if poll == nil {
+		t.Fatal("expected poll data")
+	}
+	if poll.TotalVotes != 20 || len(poll.Options) != 2 {
+		t.Fatalf("unexpected poll: %+v", poll)
+	}
+	if poll.Options[0] != (PollOption{Text: "vim", Votes: 12}) || poll.Options[1] != (PollOption{Text: "emacs", Votes: 8}) {
+		t.Errorf("unexpected options: %+v", poll.Options)
+	}
+	if want := time.UnixMilli(1700000000000).Format(timeLayout); poll.VotingEndsAt != want {
+		t.Errorf("voting_ends_at = %q, want %q", poll.VotingEndsAt, want)
+	}
+	if poll.UserSelection != "" {
+		t.Errorf("user_selection = %q, want empty", poll.UserSelection)
+	}
+}
+
+func TestExtractRedditPostWithoutPoll(t *testing.T) {
+	post, err := fixtureExtractor(postFixture).extractRedditPostFromAPI(context.Background(), testPostURL, extractOptions{})
+	if err != nil {
+		t.Fatalf("extractRedditPostFromAPI failed: %v", err)
+	}
+	if post.Poll != nil {
+		t.Errorf("expected nil poll, got %+v", post.Poll)
+	}
+}
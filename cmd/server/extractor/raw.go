@@ -0,0 +1,45 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// RawResponse captures the upstream Reddit JSON read by extractions run with
+// a context returned by WithRawResponse, for consumers that need fields this
+// package does not parse. Bodies can be large (a post with a long comment
+// thread easily reaches megabytes), so only request it when needed.
+type RawResponse struct {
+	mu   sync.Mutex
+	body json.RawMessage
+}
+
+// JSON returns the most recently captured body, or nil if nothing valid was
+// read. Bodies that are not well-formed JSON (such as truncated listings)
+// are never captured.
+func (r *RawResponse) JSON() json.RawMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.body
+}
+
+type rawKey struct{}
+
+// WithRawResponse returns a context that keeps the raw JSON body of every
+// Reddit API response read with it in the returned RawResponse. When one
+// context covers several requests, the last body wins.
+func WithRawResponse(ctx context.Context) (context.Context, *RawResponse) {
+	raw := &RawResponse{}
+	return context.WithValue(ctx, rawKey{}, raw), raw
+}
+
+func recordRawResponse(ctx context.Context, body []byte) {
+	raw, _ := ctx.Value(rawKey{}).(*RawResponse)
+	if raw == nil || !json.Valid(body) {
+		return
+	}
+	raw.mu.Lock()
+	raw.body = json.RawMessage(body)
+	raw.mu.Unlock()
+}
@@ -0,0 +1,32 @@
+package extractor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWithRawResponse(t *testing.T) {
+	e := fixtureExtractor(postFixture)
+
+	ctx, raw := WithRawResponse(context.Background())
+	if _, err := e.ExtractRedditPost(ctx, testPostURL); err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	if string(raw.JSON()) != postFixture {
+		t.Errorf("raw body not captured, got %d bytes", len(raw.JSON()))
+	}
+}
+
+func TestWithRawResponseSkipsTruncatedBody(t *testing.T) {
+	truncated := completeListingFixture[:strings.Index(completeListingFixture, `"title": "c"`)]
+	e := fixtureExtractor(truncated)
+
+	ctx, raw := WithRawResponse(context.Background())
+	if _, err := e.ExtractSubredditPosts(ctx, "https://www.reddit.com/r/golang/", "", "", 0, ""); err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if raw.JSON() != nil {
+		t.Errorf("expected invalid JSON to be skipped, got %q", raw.JSON())
+	}
+}
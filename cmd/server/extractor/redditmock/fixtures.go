@@ -0,0 +1,60 @@
+package redditmock
+
+// ListingFixture is a one-page r/golang listing holding a self post, an
+// image post, a two-image gallery and a link post.
+const ListingFixture = `{"kind": "Listing", "data": {"after": null, "dist": 4, "children": [
+	{"kind": "t3", "data": {
+		"id": "abc123", "name": "t3_abc123", "subreddit": "golang", "subreddit_name_prefixed": "r/golang",
+		"title": "How do you structure large Go services?", "author": "gopher",
+		"created_utc": 1717000000.0, "score": 128, "num_comments": 2,
+		"permalink": "/r/golang/comments/abc123/how_do_you_structure_large_go_services/",
+		"url": "https://www.reddit.com/r/golang/comments/abc123/how_do_you_structure_large_go_services/",
+		"is_self": true, "selftext": "We are at about 200k lines and the package layout is starting to hurt.",
+		"link_flair_text": "discussion"}},
+	{"kind": "t3", "data": {
+		"id": "def456", "name": "t3_def456", "subreddit": "golang", "subreddit_name_prefixed": "r/golang",
+		"title": "My gopher plush arrived", "author": "plushfan",
+		"created_utc": 1717003600.0, "score": 512, "num_comments": 0,
+		"permalink": "/r/golang/comments/def456/my_gopher_plush_arrived/",
+		"url": "https://i.redd.it/gopherplush.jpg", "post_hint": "image", "is_self": false,
+		"preview": {"images": [{"source": {"url": "https://preview.redd.it/gopherplush.jpg?width=1920&amp;s=abc", "width": 1920, "height": 1080}}]}}},
+	{"kind": "t3", "data": {
+		"id": "ghi789", "name": "t3_ghi789", "subreddit": "golang", "subreddit_name_prefixed": "r/golang",
+		"title": "Profiling screenshots", "author": "perfnerd",
+		"created_utc": 1717007200.0, "score": 64, "num_comments": 0,
+		"permalink": "/r/golang/comments/ghi789/profiling_screenshots/",
+		"url": "https://www.reddit.com/gallery/ghi789", "is_gallery": true, "is_self": false,
+		"gallery_data": {"items": [{"media_id": "flame1"}, {"media_id": "flame2"}]},
+		"media_metadata": {
+			"flame1": {"status": "valid", "e": "Image", "m": "image/png", "s": {"u": "https://preview.redd.it/flame1.png?width=2400&amp;s=def", "x": 2400, "y": 1350}},
+			"flame2": {"status": "valid", "e": "Image", "m": "image/png", "s": {"u": "https://preview.redd.it/flame2.png?width=2400&amp;s=ghi", "x": 2400, "y": 1350}}
+		}}},
+	{"kind": "t3", "data": {
+		"id": "jkl012", "name": "t3_jkl012", "subreddit": "golang", "subreddit_name_prefixed": "r/golang",
+		"title": "Go 1.24 is released", "author": "releasebot",
+		"created_utc": 1717010800.0, "score": 1024, "num_comments": 0,
+		"permalink": "/r/golang/comments/jkl012/go_124_is_released/",
+		"url": "https://go.dev/blog/go1.24", "is_self": false}}
+]}}`
+
+// PostFixture is the post abc123 of ListingFixture with a short comment
+// thread: one top-level comment with a reply, and a second top-level one.
+const PostFixture = `[
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {
+			"id": "abc123", "name": "t3_abc123", "subreddit": "golang",
+			"title": "How do you structure large Go services?", "author": "gopher",
+			"created_utc": 1717000000.0, "score": 128, "num_comments": 2,
+			"permalink": "/r/golang/comments/abc123/how_do_you_structure_large_go_services/",
+			"is_self": true, "selftext": "We are at about 200k lines and the package layout is starting to hurt."}}
+	]}},
+	{"kind": "Listing", "data": {"children": [
+		{"kind": "t1", "data": {
+			"id": "c1", "author": "pkgdesigner", "body": "Package by feature, not by layer.", "score": 40,
+			"created_utc": 1717000600.0,
+			"replies": {"kind": "Listing", "data": {"children": [
+				{"kind": "t1", "data": {"id": "c2", "author": "gopher", "body": "That matches what we ended up doing.", "score": 12, "created_utc": 1717001200.0, "replies": ""}}
+			]}}}},
+		{"kind": "t1", "data": {"id": "c3", "author": "monorepo", "body": "Keep internal/ small and boring.", "score": 8, "created_utc": 1717001800.0, "replies": ""}}
+	]}}
+]`
@@ -0,0 +1,114 @@
+// Package redditmock serves canned Reddit API responses, so code built on the
+// extractor package can be tested without reaching Reddit.
+//
+// A Server answers listing requests (/r/<subreddit>/<sort>.json) and post
+// requests (/r/<subreddit>/comments/<id>.json, or any longer path) from its
+// Fixtures, and everything else with Reddit's 404 JSON. Its Client sends
+// requests for any reddit.com host to the server instead, for use with
+// WithHTTPClient:
+//
+//	mock := redditmock.NewServer(redditmock.Fixtures{
+//		Listings: map[string]string{"golang": redditmock.ListingFixture},
+//		Posts:    map[string]string{"abc123": redditmock.PostFixture},
+//	})
+//	defer mock.Close()
+//	ext, err := extractor.NewExtractor(extractor.WithHTTPClient(mock.Client()))
+//
+// Only the JSON API is mocked. Pass extractor.APIOnly() to post extractions
+// so a failing one does not fall back to scraping the real site.
+package redditmock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// notFoundBody is the body Reddit sends with a 404 from its JSON API.
+const notFoundBody = `{"message": "Not Found", "error": 404}`
+
+// Fixtures holds the canned responses of a Server.
+type Fixtures struct {
+	// Listings maps a subreddit name, matched case-insensitively, to the
+	// listing JSON returned for every sort and page of it.
+	Listings map[string]string
+	// Posts maps a post ID, without the t3_ prefix, to the JSON returned
+	// for the post, a two-element array of the post and comment listings.
+	Posts map[string]string
+}
+
+// Server is a running mock of the Reddit JSON API.
+type Server struct {
+	*httptest.Server
+	listings map[string]string
+	posts    map[string]string
+}
+
+// NewServer starts a Server answering from fixtures. Callers must Close it.
+func NewServer(fixtures Fixtures) *Server {
+	s := &Server{
+		listings: make(map[string]string, len(fixtures.Listings)),
+		posts:    make(map[string]string, len(fixtures.Posts)),
+	}
+	for name, body := range fixtures.Listings {
+		s.listings[strings.ToLower(name)] = body
+	}
+	for id, body := range fixtures.Posts {
+		s.posts[id] = body
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	body, ok := s.lookup(r.URL.Path)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		body = notFoundBody
+	}
+	w.Write([]byte(body))
+}
+
+// lookup returns the fixture for a request path.
+func (s *Server) lookup(path string) (string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "r" {
+		return "", false
+	}
+	if parts[2] == "comments" {
+		if len(parts) < 4 {
+			return "", false
+		}
+		body, ok := s.posts[strings.TrimSuffix(parts[3], ".json")]
+		return body, ok
+	}
+	if len(parts) != 3 || !strings.HasSuffix(parts[2], ".json") {
+		return "", false
+	}
+	body, ok := s.listings[strings.ToLower(parts[1])]
+	return body, ok
+}
+
+// Client returns a client that sends requests for reddit.com and its
+// subdomains to the server, and any other request unchanged.
+func (s *Server) Client() *http.Client {
+	target := s.Server.URL[len("http://"):]
+	base := s.Server.Client().Transport
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		host := strings.ToLower(req.URL.Hostname())
+		if host == "reddit.com" || strings.HasSuffix(host, ".reddit.com") {
+			req = req.Clone(req.Context())
+			req.URL.Scheme = "http"
+			req.URL.Host = target
+			req.Host = ""
+		}
+		return base.RoundTrip(req)
+	})}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,75 @@
+package redditmock
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+func newTestExtractor(t *testing.T) *extractor.Extractor {
+	t.Helper()
+	mock := NewServer(Fixtures{
+		Listings: map[string]string{"golang": ListingFixture},
+		Posts:    map[string]string{"abc123": PostFixture},
+	})
+	t.Cleanup(mock.Close)
+	ext, err := extractor.NewExtractor(extractor.WithHTTPClient(mock.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ext
+}
+
+func TestListing(t *testing.T) {
+	ext := newTestExtractor(t)
+
+	for _, sort := range []string{"", "new"} {
+		resp, err := ext.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/GoLang/", sort, "", 0, "")
+		if err != nil {
+			t.Fatalf("ExtractSubredditPosts(%q) failed: %v", sort, err)
+		}
+		if len(resp.Posts) != 4 || resp.HasMore {
+			t.Fatalf("sort %q: got %d posts, has_more %v, want 4 and false", sort, len(resp.Posts), resp.HasMore)
+		}
+		if got := resp.Posts[2].ImageURLs; len(got) != 2 {
+			t.Errorf("gallery images = %v, want 2", got)
+		}
+	}
+}
+
+func TestPost(t *testing.T) {
+	ext := newTestExtractor(t)
+
+	post, err := ext.ExtractRedditPostWithOptions(context.Background(),
+		"https://www.reddit.com/r/golang/comments/abc123/how_do_you_structure_large_go_services/", extractor.APIOnly())
+	if err != nil {
+		t.Fatalf("ExtractRedditPostWithOptions failed: %v", err)
+	}
+	if post.Author != "gopher" || len(post.Comments) != 2 || len(post.Comments[0].Replies) != 1 {
+		t.Errorf("unexpected post: %+v", post)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	mock := NewServer(Fixtures{})
+	defer mock.Close()
+
+	resp, err := mock.Client().Get("https://old.reddit.com/r/golang/hot.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", resp.StatusCode)
+	}
+
+	ext, err := extractor.NewExtractor(extractor.WithHTTPClient(mock.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ext.ExtractRedditPostWithOptions(context.Background(), "https://www.reddit.com/r/golang/comments/zzz999/x/", extractor.APIOnly()); err == nil {
+		t.Error("expected an error for a post without a fixture")
+	}
+}
@@ -0,0 +1,46 @@
+package extractor
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RedirectError is returned when a request is redirected more often than
+// WithMaxRedirects allows, or back to a URL it already visited twice.
+type RedirectError struct {
+	// URL is the redirect target that was not followed.
+	URL string
+	// Redirects is how many redirects were followed before it.
+	Redirects int
+	// Loop is set when URL had already been requested twice.
+	Loop bool
+}
+
+func (e RedirectError) Error() string {
+	if e.Loop {
+		return fmt.Sprintf("redirect loop: %s requested again after %d redirects", e.URL, e.Redirects)
+	}
+	return fmt.Sprintf("too many redirects: stopped after %d before %s", e.Redirects, e.URL)
+}
+
+// checkRedirect is the CheckRedirect policy installed by WithMaxRedirects:
+// req is the redirect about to be followed and via the requests made so far,
+// oldest first. A URL may be revisited once, as Reddit does when it bounces
+// a request back to the same page to set its consent cookies; only a third
+// request for it counts as a loop.
+func (e *Extractor) checkRedirect(req *http.Request, via []*http.Request) error {
+	target := req.URL.String()
+	visits := 0
+	for _, prev := range via {
+		if prev.URL.String() == target {
+			visits++
+		}
+	}
+	if visits > 1 {
+		return RedirectError{URL: target, Redirects: len(via) - 1, Loop: true}
+	}
+	if len(via) > e.maxRedirects {
+		return RedirectError{URL: target, Redirects: len(via) - 1}
+	}
+	return nil
+}
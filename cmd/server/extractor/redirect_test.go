@@ -0,0 +1,120 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// redirectServer redirects /loop/a to /loop/b and back, and /chain/<n> to
+// /chain/<n+1> forever. /bounce redirects to itself once, setting a cookie
+// the way Reddit's consent bounce does, and /self redirects to itself
+// forever.
+func redirectServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/loop/a":
+			http.Redirect(w, r, "/loop/b", http.StatusFound)
+		case r.URL.Path == "/loop/b":
+			http.Redirect(w, r, "/loop/a", http.StatusFound)
+		case r.URL.Path == "/bounce":
+			if _, err := r.Cookie("consent"); err != nil {
+				http.SetCookie(w, &http.Cookie{Name: "consent", Value: "1", Path: "/"})
+				http.Redirect(w, r, "/bounce", http.StatusFound)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><h1>Bounced</h1></body></html>`))
+		case r.URL.Path == "/self":
+			http.Redirect(w, r, "/self", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/chain/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/chain/"))
+			http.Redirect(w, r, fmt.Sprintf("/chain/%d", n+1), http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+}
+
+func TestWithMaxRedirects(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+
+	cases := []struct {
+		name      string
+		opts      []Option
+		path      string
+		redirects int
+		loop      bool
+	}{
+		{"loop", []Option{WithMaxRedirects(5)}, "/loop/a", 3, true},
+		{"self loop", []Option{WithMaxRedirects(5)}, "/self", 1, true},
+		{"chain", []Option{WithMaxRedirects(3)}, "/chain/0", 3, false},
+		{"no redirects", []Option{WithMaxRedirects(0)}, "/chain/0", 0, false},
+	}
+	for _, tc := range cases {
+		e := mustNewExtractor(tc.opts...)
+		req, _ := http.NewRequest(http.MethodGet, server.URL+tc.path, nil)
+		_, err := e.doRequest(req)
+		var redirectErr RedirectError
+		if !errors.As(err, &redirectErr) {
+			t.Fatalf("%s: err = %v, want RedirectError", tc.name, err)
+		}
+		if redirectErr.Redirects != tc.redirects || redirectErr.Loop != tc.loop {
+			t.Errorf("%s: got %+v, want %d redirects, loop %v", tc.name, redirectErr, tc.redirects, tc.loop)
+		}
+	}
+
+	// Without the option, the net/http default applies.
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/chain/0", nil)
+	_, err := mustNewExtractor().doRequest(req)
+	if err == nil || errors.As(err, new(RedirectError)) || !strings.Contains(err.Error(), "stopped after 10 redirects") {
+		t.Errorf("default: err = %v, want net/http's redirect limit", err)
+	}
+}
+
+func TestWithMaxRedirectsCopiesClient(t *testing.T) {
+	client := &http.Client{}
+	e := mustNewExtractor(WithHTTPClient(client), WithMaxRedirects(2))
+	if client.CheckRedirect != nil {
+		t.Error("the caller's client was modified")
+	}
+	if e.httpClient.CheckRedirect == nil {
+		t.Error("the redirect policy was not installed")
+	}
+}
+
+func TestWithMaxRedirectsHTML(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	e := mustNewExtractor(WithAllowedHosts([]string{u.Hostname()}), WithMaxRedirects(5))
+
+	_, err := e.extractRedditPostFromHTML(context.Background(), server.URL+"/self")
+	var redirectErr RedirectError
+	if !errors.As(err, &redirectErr) || !redirectErr.Loop {
+		t.Fatalf("err = %v, want a redirect loop error", err)
+	}
+}
+
+func TestWithMaxRedirectsAllowsCookieBounce(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	e := mustNewExtractor(WithAllowedHosts([]string{u.Hostname()}), WithMaxRedirects(5))
+
+	post, err := e.extractRedditPostFromHTML(context.Background(), server.URL+"/bounce")
+	if err != nil {
+		t.Fatalf("extractRedditPostFromHTML failed on the cookie bounce: %v", err)
+	}
+	if post.Title != "Bounced" {
+		t.Errorf("title = %q, want the page after the bounce", post.Title)
+	}
+}
@@ -0,0 +1,32 @@
+package extractor
+
+import (
+	"context"
+	"log"
+	"os"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying id. Log lines written by
+// extractions run with it are tagged "req=<id>" so they can be matched to
+// the request that caused them.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID set by WithRequestID, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newLogger returns the stderr logger of an extraction component, tagging
+// its lines with the request ID carried by ctx, if any.
+func newLogger(ctx context.Context, component string) *log.Logger {
+	prefix := "[" + component + "] "
+	if id := RequestIDFromContext(ctx); id != "" {
+		prefix += "req=" + id + " "
+	}
+	return log.New(os.Stderr, prefix, log.LstdFlags|log.Lmsgprefix)
+}
@@ -0,0 +1,25 @@
+package extractor
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestIDRoundTrip(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "abc123")
+	if got := RequestIDFromContext(ctx); got != "abc123" {
+		t.Fatalf("RequestIDFromContext = %q, want abc123", got)
+	}
+	if got := RequestIDFromContext(context.Background()); got != "" {
+		t.Fatalf("RequestIDFromContext on a plain context = %q, want empty", got)
+	}
+}
+
+func TestNewLoggerTagsRequestID(t *testing.T) {
+	if got := newLogger(WithRequestID(context.Background(), "abc123"), "subreddit").Prefix(); got != "[subreddit] req=abc123 " {
+		t.Errorf("prefix = %q", got)
+	}
+	if got := newLogger(context.Background(), "subreddit").Prefix(); got != "[subreddit] " {
+		t.Errorf("prefix = %q", got)
+	}
+}
@@ -0,0 +1,131 @@
+package extractor
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Default backoff of retried requests: the first retry waits up to
+// defaultRetryBaseDelay and each further one up to defaultRetryMultiplier
+// times as long as the last. No backoff exceeds maxRetryDelay.
+const (
+	defaultRetryBaseDelay  = 500 * time.Millisecond
+	defaultRetryMultiplier = 2
+	maxRetryDelay          = 30 * time.Second
+)
+
+// JitterMode selects how retry backoffs are randomized, which keeps many
+// clients that failed together from retrying in lockstep.
+type JitterMode int
+
+const (
+	// JitterFull waits a random time between zero and the backoff. It is
+	// the default.
+	JitterFull JitterMode = iota
+	// JitterEqual waits half the backoff plus a random time up to the other
+	// half.
+	JitterEqual
+	// JitterNone waits exactly the backoff.
+	JitterNone
+)
+
+// doRequest sends req, retrying transient failures as configured by
+// WithRetry and WithRetryBudget. A retry is skipped when its backoff would
+// run past the request context's deadline or the retry budget; the last
+// response or error is then returned as is, so callers see the same failure
+// they would have seen without retries. With a circuit breaker configured,
+// the request and its retries count as one outcome, and an open circuit
+// fails the request before anything is sent.
+func (e *Extractor) doRequest(req *http.Request) (*http.Response, error) {
+	if e.breaker == nil {
+		return e.sendWithRetries(req)
+	}
+	probe, err := e.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.sendWithRetries(req)
+	e.breaker.record(req.Context(), probe, resp, err)
+	return resp, err
+}
+
+// sendWithRetries is doRequest without the circuit breaker.
+func (e *Extractor) sendWithRetries(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := e.clock.Now()
+	for attempt := 0; ; attempt++ {
+		resp, err := e.send(req)
+		if attempt >= e.retries || !shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+		delay := e.retryDelay(attempt)
+		if !e.retryFits(ctx, start, delay) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := e.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry reports whether a request that ended with resp or err is worth
+// sending again: transport errors other than the context ending, rate
+// limiting and the gateway errors Reddit returns under load.
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns the wait before retry number attempt+1: the exponential
+// backoff for attempt, capped at maxRetryDelay, with the jitter applied.
+func (e *Extractor) retryDelay(attempt int) time.Duration {
+	backoff := min(float64(e.retryBaseDelay)*math.Pow(e.retryMultiplier, float64(attempt)), float64(maxRetryDelay))
+	switch e.retryJitter {
+	case JitterNone:
+	case JitterEqual:
+		backoff = backoff/2 + e.random()*backoff/2
+	default:
+		backoff *= e.random()
+	}
+	return time.Duration(backoff)
+}
+
+// retryFits reports whether waiting delay before the next attempt stays
+// within both the retry budget, counted from start on the Extractor's
+// clock, and ctx's deadline, which is wall time like every context's.
+func (e *Extractor) retryFits(ctx context.Context, start time.Time, delay time.Duration) bool {
+	if e.retryBudget > 0 && e.clock.Now().Sub(start)+delay > e.retryBudget {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return false
+	}
+	return true
+}
+
+// sleep waits for d on the Extractor's clock, or until ctx is done,
+// whichever comes first.
+func (e *Extractor) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.clock.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,201 @@
+package extractor
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const retryListingURL = "https://www.reddit.com/r/golang/"
+
+// statusSequence answers with the given statuses in order, repeating the
+// last one, and an empty listing for 200.
+func statusSequence(calls *atomic.Int64, statuses ...int) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		n := int(calls.Add(1)) - 1
+		status := statuses[min(n, len(statuses)-1)]
+		return cannedResponse(req, status, `{"kind": "Listing", "data": {"children": []}}`), nil
+	})}
+}
+
+func TestWithRetry(t *testing.T) {
+	var calls atomic.Int64
+	e := mustNewExtractor(WithHTTPClient(statusSequence(&calls, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)), WithRetry(3), WithBackoff(time.Millisecond, 2))
+
+	if _, err := e.ExtractSubredditPosts(context.Background(), retryListingURL, "", "", 0, ""); err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("got %d requests, want 3", calls.Load())
+	}
+}
+
+func TestWithRetryStopsAfterRetries(t *testing.T) {
+	var calls atomic.Int64
+	e := mustNewExtractor(WithHTTPClient(statusSequence(&calls, http.StatusBadGateway)), WithRetry(2), WithBackoff(time.Millisecond, 2))
+
+	if _, err := e.ExtractSubredditPosts(context.Background(), retryListingURL, "", "", 0, ""); err == nil {
+		t.Fatal("expected an error once retries are exhausted")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("got %d requests, want 3", calls.Load())
+	}
+}
+
+func TestWithRetrySkipsPermanentErrors(t *testing.T) {
+	var calls atomic.Int64
+	e := mustNewExtractor(WithHTTPClient(statusSequence(&calls, http.StatusInternalServerError)), WithRetry(3), WithBackoff(time.Millisecond, 2))
+
+	e.ExtractSubredditPosts(context.Background(), retryListingURL, "", "", 0, "")
+	if calls.Load() != 1 {
+		t.Errorf("got %d requests, want 1", calls.Load())
+	}
+}
+
+func TestWithRetryHonorsDeadline(t *testing.T) {
+	var calls atomic.Int64
+	e := mustNewExtractor(WithHTTPClient(statusSequence(&calls, http.StatusServiceUnavailable)), WithRetry(5), WithJitter(JitterNone))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := e.ExtractSubredditPosts(ctx, retryListingURL, "", "", 0, "")
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("expected the 503 to be returned")
+	}
+	if ctx.Err() != nil {
+		t.Errorf("returned after the deadline, in %v", elapsed)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("got %d requests, want 1: the first backoff exceeds the deadline", calls.Load())
+	}
+}
+
+func TestWithRetryBudget(t *testing.T) {
+	var calls atomic.Int64
+	e := mustNewExtractor(WithHTTPClient(statusSequence(&calls, http.StatusServiceUnavailable)), WithRetry(10), WithRetryBudget(50*time.Millisecond),
+		WithBackoff(20*time.Millisecond, 2), WithJitter(JitterNone))
+
+	// Backoffs of 20ms and 40ms add up to more than the budget, so only the
+	// first retry is sent.
+	if _, err := e.ExtractSubredditPosts(context.Background(), retryListingURL, "", "", 0, ""); err == nil {
+		t.Fatal("expected an error once the budget is spent")
+	}
+	if calls.Load() != 2 {
+		t.Errorf("got %d requests, want 2", calls.Load())
+	}
+}
+
+func TestWithRetryBudgetUsesClock(t *testing.T) {
+	var calls atomic.Int64
+	clock := newFakeClock()
+	e := mustNewExtractor(WithHTTPClient(statusSequence(&calls, http.StatusServiceUnavailable)), WithClock(clock),
+		WithRetry(10), WithRetryBudget(50*time.Second), WithBackoff(10*time.Second, 2), WithJitter(JitterNone))
+
+	// Backoffs of 10s, 20s and 30s: the third would run past the budget.
+	// The waits happen on the fake clock, so the test does not sleep.
+	start := time.Now()
+	if _, err := e.ExtractSubredditPosts(context.Background(), retryListingURL, "", "", 0, ""); err == nil {
+		t.Fatal("expected an error once the budget is spent")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("got %d requests, want 3", calls.Load())
+	}
+	if waited := clock.Now().Sub(newFakeClock().Now()); waited != 30*time.Second {
+		t.Errorf("clock advanced by %v, want the 30s of backoff", waited)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v of real time", elapsed)
+	}
+}
+
+func TestWithRetryDeadlineUsesWallTime(t *testing.T) {
+	var calls atomic.Int64
+	// The fake clock stands years in the past, so only wall time tells
+	// that the 10s backoff runs past the deadline.
+	e := mustNewExtractor(WithHTTPClient(statusSequence(&calls, http.StatusServiceUnavailable)), WithClock(newFakeClock()),
+		WithRetry(5), WithBackoff(10*time.Second, 2), WithJitter(JitterNone))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := e.ExtractSubredditPosts(ctx, retryListingURL, "", "", 0, ""); err == nil {
+		t.Fatal("expected the 503 to be returned")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("got %d requests, want 1: the first backoff exceeds the deadline", calls.Load())
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	e := mustNewExtractor(WithJitter(JitterNone))
+	for attempt, want := range []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second} {
+		if got := e.retryDelay(attempt); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+	if got := e.retryDelay(100); got != maxRetryDelay {
+		t.Errorf("retryDelay(100) = %v, want the %v cap", got, maxRetryDelay)
+	}
+}
+
+func TestWithBackoff(t *testing.T) {
+	e := mustNewExtractor(WithBackoff(100*time.Millisecond, 3), WithJitter(JitterNone))
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond} {
+		if got := e.retryDelay(attempt); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+
+	e = mustNewExtractor(WithBackoff(0, 0.5))
+	if e.retryBaseDelay != defaultRetryBaseDelay || e.retryMultiplier != defaultRetryMultiplier {
+		t.Errorf("backoff = %v x%v, want the defaults", e.retryBaseDelay, e.retryMultiplier)
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	const attempt = 2 // a 2s backoff with the defaults
+	backoff := 2 * time.Second
+	tests := []struct {
+		name     string
+		opts     []Option
+		min, max time.Duration
+	}{
+		{"default is full", nil, 0, backoff},
+		{"full", []Option{WithJitter(JitterFull)}, 0, backoff},
+		{"equal", []Option{WithJitter(JitterEqual)}, backoff / 2, backoff},
+		{"none", []Option{WithJitter(JitterNone)}, backoff, backoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := mustNewExtractor(tt.opts...)
+			for i := 0; i < 1000; i++ {
+				if got := e.retryDelay(attempt); got < tt.min || got > tt.max {
+					t.Fatalf("retryDelay(%d) = %v, want within [%v, %v]", attempt, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestWithRandSource(t *testing.T) {
+	backoffs := []float64{float64(500 * time.Millisecond), float64(time.Second), float64(2 * time.Second)}
+	for _, mode := range []JitterMode{JitterFull, JitterEqual} {
+		e := mustNewExtractor(WithRandSource(rand.NewPCG(1, 2)), WithJitter(mode))
+		ref := rand.New(rand.NewPCG(1, 2))
+		for attempt, backoff := range backoffs {
+			r := ref.Float64()
+			want := time.Duration(backoff * r)
+			if mode == JitterEqual {
+				want = time.Duration(backoff/2 + r*backoff/2)
+			}
+			if got := e.retryDelay(attempt); got != want {
+				t.Errorf("mode %d: retryDelay(%d) = %v, want %v", mode, attempt, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package extractor
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// URLKind identifies which kind of Reddit page a URL points at.
+type URLKind string
+
+// URL kinds recognized by ExtractURL.
+const (
+	URLKindPost        URLKind = "post"
+	URLKindSubreddit   URLKind = "subreddit"
+	URLKindMultireddit URLKind = "multireddit"
+)
+
+// ExtractResult is the tagged result of ExtractURL. Kind tells which of Post
+// or Listing is set.
+type ExtractResult struct {
+	Kind    URLKind                `json:"kind"`
+	Post    *RedditPost            `json:"post,omitempty"`
+	Listing *SubredditListResponse `json:"listing,omitempty"`
+}
+
+// ExtractURL extracts rawURL using the default Extractor.
+func ExtractURL(ctx context.Context, rawURL string) (*ExtractResult, error) {
+	return defaultExtractor.ExtractURL(ctx, rawURL)
+}
+
+// ExtractURL inspects rawURL and dispatches to post extraction or a listing
+// fetch with default sort and limit. Multireddits (/r/golang+rust) are
+// fetched like a single subreddit. URLs of any other shape yield a
+// ValidationError.
+func (e *Extractor) ExtractURL(ctx context.Context, rawURL string) (*ExtractResult, error) {
+	kind, err := e.classifyRedditURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	result := &ExtractResult{Kind: kind}
+	switch kind {
+	case URLKindPost:
+		result.Post, err = e.ExtractRedditPost(ctx, rawURL)
+	default:
+		result.Listing, err = e.ExtractSubredditPosts(ctx, rawURL, "", "", 0, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// classifyRedditURL reports which kind of page rawURL points at.
+func (e *Extractor) classifyRedditURL(rawURL string) (URLKind, error) {
+	if err := e.ValidateRedditURL(rawURL); err != nil {
+		return "", ValidationError{Message: err.Error()}
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ValidationError{Message: "invalid url"}
+	}
+	for _, part := range strings.Split(strings.Trim(parsed.Path, "/"), "/") {
+		if part != "comments" {
+			continue
+		}
+		if _, _, ok := parseRedditURL(rawURL); !ok {
+			return "", errNotAPost
+		}
+		return URLKindPost, nil
+	}
+	subreddit, err := parseSubredditURL(rawURL)
+	if err != nil {
+		return "", ValidationError{Message: "unsupported reddit url"}
+	}
+	if strings.Contains(subreddit, "+") {
+		return URLKindMultireddit, nil
+	}
+	return URLKindSubreddit, nil
+}
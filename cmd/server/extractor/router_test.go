@@ -0,0 +1,63 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestClassifyRedditURL(t *testing.T) {
+	testCases := []struct {
+		url     string
+		want    URLKind
+		wantErr bool
+	}{
+		{url: "https://www.reddit.com/r/golang/comments/abc123/title/", want: URLKindPost},
+		{url: "https://www.reddit.com/r/golang/", want: URLKindSubreddit},
+		{url: "https://www.reddit.com/r/golang", want: URLKindSubreddit},
+		{url: "https://www.reddit.com/r/golang+rust/", want: URLKindMultireddit},
+		{url: "https://www.reddit.com/r/golang/comments/", wantErr: true},
+		{url: "https://www.reddit.com/user/spez/", wantErr: true},
+		{url: "https://example.com/r/golang/", wantErr: true},
+		{url: "", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			got, err := mustNewExtractor().classifyRedditURL(tc.url)
+			if tc.wantErr {
+				var validationErr ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("err = %v, want ValidationError", err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("classifyRedditURL() = %q, %v; want %q", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtractURLDispatches(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/r/golang/hot.json" {
+			return cannedResponse(req, http.StatusOK, `{"kind": "Listing", "data": {"children": [
+				{"kind": "t3", "data": {"title": "a", "permalink": "/r/golang/comments/aaa/a/"}}
+			]}}`), nil
+		}
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	post, err := e.ExtractURL(context.Background(), "https://www.reddit.com/r/golang/comments/abc123/title/")
+	if err != nil || post.Kind != URLKindPost || post.Post == nil || post.Listing != nil {
+		t.Fatalf("unexpected post result: %+v, %v", post, err)
+	}
+
+	listing, err := e.ExtractURL(context.Background(), "https://www.reddit.com/r/golang/")
+	if err != nil || listing.Kind != URLKindSubreddit || listing.Listing == nil || len(listing.Listing.Posts) != 1 {
+		t.Fatalf("unexpected listing result: %+v, %v", listing, err)
+	}
+}
@@ -0,0 +1,153 @@
+package extractor
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values of SubredditListResponse.Source.
+const (
+	SourceJSON = "json"
+	SourceRSS  = "rss"
+)
+
+// rssFeed is the part of Reddit's Atom feed (served as .rss) that maps onto
+// SubredditPost.
+type rssFeed struct {
+	Entries []struct {
+		ID     string `xml:"id"`
+		Title  string `xml:"title"`
+		Author struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Category struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+		Link struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+	} `xml:"entry"`
+}
+
+// ExtractSubredditPostsRSS fetches a subreddit feed using the default
+// Extractor.
+func ExtractSubredditPostsRSS(ctx context.Context, subredditURL, sort string) (*SubredditListResponse, error) {
+	return defaultExtractor.ExtractSubredditPostsRSS(ctx, subredditURL, sort)
+}
+
+// ExtractSubredditPostsRSS fetches the first page of a subreddit listing
+// from its RSS (Atom) feed, which often still works when the JSON API is
+// blocked. Feed entries only carry the ID, title, link, subreddit, author
+// and published time of each post, so every other SubredditPost field, such
+// as Score, Comments and ImageURLs, is left empty, and the feed cannot be
+// paged: HasMore is always false.
+func (e *Extractor) ExtractSubredditPostsRSS(ctx context.Context, subredditURL, sort string) (result *SubredditListResponse, err error) {
+	ctx, span := e.tracer.Start(ctx, "extractor.ExtractSubredditPostsRSS")
+	span.SetAttribute("reddit.url", subredditURL)
+	span.SetAttribute("reddit.sort", sort)
+	defer func() {
+		if result != nil {
+			span.SetAttribute("reddit.post_count", len(result.Posts))
+		}
+		endSpan(span, err)
+	}()
+
+	logger := newLogger(ctx, "subreddit")
+
+	subreddit, err := e.subredditFromURL(subredditURL)
+	if err != nil {
+		logger.Printf("validation error: url=%s, err=%v", subredditURL, err)
+		return nil, ValidationError{Message: err.Error()}
+	}
+	normalizedSort := normalizeSubredditSort(sort)
+	if strings.TrimSpace(sort) != "" && normalizedSort == "" {
+		logger.Printf("invalid sort parameter: sort=%s, subreddit=%s", sort, subreddit)
+		return nil, ValidationError{Message: "invalid sort"}
+	}
+	if normalizedSort == "" {
+		normalizedSort = e.defaultSort
+	}
+
+	posts, err := e.fetchRSSPosts(ctx, subreddit, normalizedSort, e.defaultLimit, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("success: subreddit=%s, source=rss, returned=%d", subreddit, len(posts))
+	return &SubredditListResponse{Source: SourceRSS, Posts: posts}, nil
+}
+
+// fetchRSSPosts requests the feed of subreddit sorted by sort and converts
+// its entries into SubredditPosts. An unavailable subreddit yields no posts.
+func (e *Extractor) fetchRSSPosts(ctx context.Context, subreddit, sort string, limit int, logger *log.Logger) ([]SubredditPost, error) {
+	feedURL := redditAPIURL("rss", url.Values{"limit": {strconv.Itoa(limit)}}, "r", subreddit, sort)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		logger.Printf("request creation failed: %v", err)
+		return nil, err
+	}
+	e.setAPIHeaders(req)
+
+	resp, err := e.doRequest(req)
+	if err != nil {
+		logger.Printf("rss request failed: subreddit=%s, err=%v", subreddit, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			logger.Printf("subreddit unavailable: subreddit=%s, status=%d", subreddit, resp.StatusCode)
+			return []SubredditPost{}, nil
+		}
+		logger.Printf("unexpected rss response: subreddit=%s, status=%d", subreddit, resp.StatusCode)
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	body, err := readBody(ctx, resp.Body)
+	if err != nil {
+		logger.Printf("body read failed: subreddit=%s, err=%v", subreddit, err)
+		return nil, err
+	}
+	var feed rssFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		logger.Printf("rss decode failed: subreddit=%s, err=%v", subreddit, err)
+		return nil, fmt.Errorf("decode rss feed: %w", err)
+	}
+	return parseRSSPosts(feed, logger), nil
+}
+
+// parseRSSPosts converts feed entries into SubredditPosts, dropping entries
+// without a usable post link.
+func parseRSSPosts(feed rssFeed, logger *log.Logger) []SubredditPost {
+	posts := make([]SubredditPost, 0, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		var postLink string
+		if u, err := url.Parse(strings.TrimSpace(entry.Link.Href)); err == nil {
+			postLink = buildRedditPostLink(u.Path)
+		}
+		if postLink == "" {
+			logger.Printf("invalid rss link filtered: title=%s, link=%s", entry.Title, entry.Link.Href)
+			continue
+		}
+		post := SubredditPost{
+			ID:        canonicalPostID("", entry.ID),
+			Title:     strings.TrimSpace(entry.Title),
+			Subreddit: strings.TrimSpace(entry.Category.Term),
+			PostLink:  postLink,
+			Author:    strings.TrimPrefix(strings.TrimSpace(entry.Author.Name), "/u/"),
+		}
+		if published, err := time.Parse(time.RFC3339, strings.TrimSpace(entry.Published)); err == nil {
+			post.PublishedTime = published.Local().Format(timeLayout)
+		}
+		posts = append(posts, post)
+	}
+	return posts
+}
@@ -0,0 +1,183 @@
+package extractor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rssFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+  <category term="golang" label="r/golang"/>
+  <updated>2024-05-29T17:00:00+00:00</updated>
+  <id>/r/golang/.rss</id>
+  <link rel="self" href="https://www.reddit.com/r/golang/.rss" type="application/atom+xml" />
+  <title>The Go Programming Language</title>
+  <entry>
+    <author><name>/u/gopher</name><uri>https://www.reddit.com/user/gopher</uri></author>
+    <category term="golang" label="r/golang"/>
+    <content type="html">&lt;p&gt;hello&lt;/p&gt;</content>
+    <id>t3_abc123</id>
+    <link href="https://www.reddit.com/r/golang/comments/abc123/fixture_post/" />
+    <updated>2024-05-29T16:26:40+00:00</updated>
+    <published>2024-05-29T16:26:40+00:00</published>
+    <title>Fixture post</title>
+  </entry>
+  <entry>
+    <author><name>/u/releasebot</name></author>
+    <category term="golang" label="r/golang"/>
+    <id>t3_def456</id>
+    <link href="https://www.reddit.com/r/golang/comments/def456/go_is_released/" />
+    <published>2024-05-29T15:00:00+00:00</published>
+    <title>Go is released</title>
+  </entry>
+  <entry>
+    <id>t3_bad</id>
+    <link href="https://example.com/elsewhere" />
+    <title>not a post</title>
+  </entry>
+</feed>`
+
+func TestExtractSubredditPostsRSS(t *testing.T) {
+	var requested string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.String()
+		resp := cannedResponse(req, http.StatusOK, rssFixture)
+		resp.Header.Set("Content-Type", "application/atom+xml; charset=UTF-8")
+		return resp, nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	resp, err := e.ExtractSubredditPostsRSS(context.Background(), "https://www.reddit.com/r/golang/", "new")
+	if err != nil {
+		t.Fatalf("ExtractSubredditPostsRSS failed: %v", err)
+	}
+	if want := "https://www.reddit.com/r/golang/new.rss?limit=20"; requested != want {
+		t.Errorf("requested %q, want %q", requested, want)
+	}
+	if resp.Source != SourceRSS {
+		t.Errorf("source = %q, want rss", resp.Source)
+	}
+	if len(resp.Posts) != 2 || resp.HasMore {
+		t.Fatalf("got %d posts, has_more %v, want 2 and false: %+v", len(resp.Posts), resp.HasMore, resp.Posts)
+	}
+	want := SubredditPost{
+		ID:            "abc123",
+		Title:         "Fixture post",
+		Subreddit:     "golang",
+		Author:        "gopher",
+		PublishedTime: time.Date(2024, 5, 29, 16, 26, 40, 0, time.UTC).Local().Format(timeLayout),
+		PostLink:      "https://www.reddit.com/r/golang/comments/abc123/fixture_post/",
+	}
+	if got := resp.Posts[0]; got.ID != want.ID || got.Title != want.Title || got.Subreddit != want.Subreddit ||
+		got.Author != want.Author || got.PublishedTime != want.PublishedTime || got.PostLink != want.PostLink {
+		t.Errorf("post = %+v, want %+v", got, want)
+	}
+}
+
+func TestExtractSubredditPostsRSSInvalidFeed(t *testing.T) {
+	e := fixtureExtractor("<feed><entry>")
+	if _, err := e.ExtractSubredditPostsRSS(context.Background(), "https://www.reddit.com/r/golang/", ""); err == nil {
+		t.Fatal("expected an error for a malformed feed")
+	}
+}
+
+func TestWithFallbackToRSS(t *testing.T) {
+	blockPage := func(req *http.Request) *http.Response {
+		resp := cannedResponse(req, http.StatusOK, "<html><body>blocked</body></html>")
+		resp.Header.Set("Content-Type", "text/html")
+		return resp
+	}
+	forbidden := func(req *http.Request) *http.Response {
+		return cannedResponse(req, http.StatusForbidden, `{"message": "Forbidden", "error": 403}`)
+	}
+	for _, tt := range []struct {
+		name string
+		json func(*http.Request) *http.Response
+	}{
+		{"block page", blockPage},
+		{"403", forbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				paths = append(paths, req.URL.Path)
+				if strings.HasSuffix(req.URL.Path, ".json") {
+					return tt.json(req), nil
+				}
+				return cannedResponse(req, http.StatusOK, rssFixture), nil
+			})}
+			e := mustNewExtractor(WithHTTPClient(client), WithFallbackToRSS(true))
+
+			resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+			if err != nil {
+				t.Fatalf("ExtractSubredditPosts failed: %v", err)
+			}
+			if resp.Source != SourceRSS || len(resp.Posts) != 2 || resp.Posts[0].Title != "Fixture post" {
+				t.Errorf("unexpected response: %+v", resp)
+			}
+			if want := []string{"/r/golang/hot.json", "/r/golang/hot.rss"}; len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+				t.Errorf("paths = %v, want %v", paths, want)
+			}
+		})
+	}
+}
+
+func TestWithoutFallbackToRSS(t *testing.T) {
+	e := fixtureExtractor("<html><body>blocked</body></html>")
+	if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, ""); err == nil {
+		t.Error("expected the block to be returned")
+	}
+
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return cannedResponse(req, http.StatusForbidden, `{"message": "Forbidden", "error": 403}`), nil
+	})}
+	resp, err := mustNewExtractor(WithHTTPClient(client)).ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/private/", "", "", 0, "")
+	if err != nil || resp.Source != SourceJSON || len(resp.Posts) != 0 {
+		t.Errorf("403 without the fallback: resp %+v, err %v, want an empty json listing", resp, err)
+	}
+}
+
+func TestFallbackToRSSFailure(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, ".json") {
+			return cannedResponse(req, http.StatusForbidden, `{"message": "Forbidden", "error": 403}`), nil
+		}
+		return cannedResponse(req, http.StatusServiceUnavailable, ""), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithFallbackToRSS(true))
+
+	resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/private/", "", "", 0, "")
+	if err != nil || resp.Source != SourceJSON || len(resp.Posts) != 0 {
+		t.Errorf("403 with a failed fallback: resp %+v, err %v, want an empty json listing", resp, err)
+	}
+}
+
+func TestFallbackToRSSAppliesOptions(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, ".json") {
+			return cannedResponse(req, http.StatusForbidden, `{"message": "Forbidden", "error": 403}`), nil
+		}
+		return cannedResponse(req, http.StatusOK, rssFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithFallbackToRSS(true))
+
+	resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "", ReportFieldCoverage())
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if resp.FieldCoverage["posts"] != 2 || resp.FieldCoverage["title"] != 2 || resp.FieldCoverage["score"] != 0 {
+		t.Errorf("field coverage = %v, want 2 posts with titles and no scores", resp.FieldCoverage)
+	}
+
+	// The feed tells neither images nor self posts apart, so neither filter
+	// can keep anything.
+	for _, opt := range []ExtractOption{ImagesOnly(), SelfOnly()} {
+		resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "", opt)
+		if err != nil || resp.Source != SourceRSS || len(resp.Posts) != 0 {
+			t.Errorf("filtered rss listing: resp %+v, err %v, want no posts", resp, err)
+		}
+	}
+}
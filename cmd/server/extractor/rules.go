@@ -0,0 +1,129 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrSubredditNotFound is returned when a subreddit does not exist or
+	// has been banned.
+	ErrSubredditNotFound = errors.New("subreddit not found")
+	// ErrSubredditPrivate is returned for private subreddits, which only
+	// their approved members can read.
+	ErrSubredditPrivate = errors.New("subreddit is private")
+	// ErrLoginRequired is returned when Reddit only answers an endpoint for
+	// logged-in users, as it does for the flairs of some subreddits.
+	ErrLoginRequired = errors.New("reddit requires a logged-in user for this request")
+)
+
+// Rule is one of a subreddit's posting rules.
+type Rule struct {
+	ShortName   string `json:"short_name"`
+	Description string `json:"description,omitempty"`
+	// Kind is what the rule applies to: "link" for posts, "comment" or
+	// "all".
+	Kind string `json:"kind"`
+}
+
+// Flair is a link flair that posts in a subreddit can be given.
+type Flair struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// TextEditable is set when the poster may change the text, and ModOnly
+	// when only moderators may assign the flair.
+	TextEditable    bool   `json:"text_editable,omitempty"`
+	ModOnly         bool   `json:"mod_only,omitempty"`
+	BackgroundColor string `json:"background_color,omitempty"`
+	TextColor       string `json:"text_color,omitempty"`
+}
+
+type redditRulesResponse struct {
+	Rules []struct {
+		ShortName   string `json:"short_name"`
+		Description string `json:"description"`
+		Kind        string `json:"kind"`
+	} `json:"rules"`
+}
+
+type redditFlair struct {
+	ID              string `json:"id"`
+	Text            string `json:"text"`
+	TextEditable    bool   `json:"text_editable"`
+	ModOnly         bool   `json:"mod_only"`
+	BackgroundColor string `json:"background_color"`
+	TextColor       string `json:"text_color"`
+}
+
+// ExtractSubredditRules fetches subreddit rules using the default Extractor.
+func ExtractSubredditRules(ctx context.Context, subreddit string) ([]Rule, error) {
+	return defaultExtractor.ExtractSubredditRules(ctx, subreddit)
+}
+
+// ExtractSubredditRules fetches the rules of a subreddit, given by name, in
+// the order the subreddit lists them.
+func (e *Extractor) ExtractSubredditRules(ctx context.Context, subreddit string) ([]Rule, error) {
+	if !subredditNameRE.MatchString(subreddit) {
+		return nil, ValidationError{Message: "invalid subreddit name"}
+	}
+
+	var resp redditRulesResponse
+	status, err := e.fetchJSON(ctx, redditAPIURL("json", nil, "r", subreddit, "about", "rules"), &resp)
+	if err != nil {
+		return nil, subredditStatusError(status, err)
+	}
+	rules := make([]Rule, 0, len(resp.Rules))
+	for _, r := range resp.Rules {
+		rules = append(rules, Rule{ShortName: r.ShortName, Description: r.Description, Kind: r.Kind})
+	}
+	return rules, nil
+}
+
+// ExtractSubredditFlairs fetches subreddit link flairs using the default
+// Extractor.
+func ExtractSubredditFlairs(ctx context.Context, subreddit string) ([]Flair, error) {
+	return defaultExtractor.ExtractSubredditFlairs(ctx, subreddit)
+}
+
+// ExtractSubredditFlairs fetches the link flairs of a subreddit, given by
+// name, from the current flair endpoint, falling back to the older one for
+// subreddits the current one does not know. Many subreddits only show their
+// flairs to logged-in users, which yields ErrLoginRequired.
+func (e *Extractor) ExtractSubredditFlairs(ctx context.Context, subreddit string) ([]Flair, error) {
+	if !subredditNameRE.MatchString(subreddit) {
+		return nil, ValidationError{Message: "invalid subreddit name"}
+	}
+
+	var resp []redditFlair
+	status, err := e.fetchJSON(ctx, redditAPIURL("json", nil, "r", subreddit, "api", "link_flair_v2"), &resp)
+	if status == http.StatusNotFound {
+		status, err = e.fetchJSON(ctx, redditAPIURL("json", nil, "r", subreddit, "api", "link_flair"), &resp)
+	}
+	if err != nil {
+		return nil, subredditStatusError(status, err)
+	}
+	flairs := make([]Flair, 0, len(resp))
+	for _, f := range resp {
+		flairs = append(flairs, Flair(f))
+	}
+	return flairs, nil
+}
+
+// subredditStatusError maps the failure of a subreddit endpoint to
+// ErrSubredditNotFound, ErrSubredditPrivate or ErrLoginRequired where the
+// status and Reddit's error object tell which applies, and returns err
+// otherwise.
+func subredditStatusError(status int, err error) error {
+	var upstream UpstreamError
+	errors.As(err, &upstream)
+	switch {
+	case status == http.StatusNotFound || upstream.Reason == "banned":
+		return ErrSubredditNotFound
+	case status == http.StatusForbidden && upstream.Reason == "private":
+		return ErrSubredditPrivate
+	case status == http.StatusForbidden:
+		return ErrLoginRequired
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestExtractSubredditRules(t *testing.T) {
+	var requested string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.String()
+		return cannedResponse(req, http.StatusOK, `{"rules": [
+			{"kind": "link", "short_name": "Go related", "description": "Posts must be about Go.", "priority": 0},
+			{"kind": "all", "short_name": "Be civil", "description": "", "priority": 1}
+		], "site_rules": ["Spam"]}`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	rules, err := e.ExtractSubredditRules(context.Background(), "golang")
+	if err != nil {
+		t.Fatalf("ExtractSubredditRules failed: %v", err)
+	}
+	if want := "https://www.reddit.com/r/golang/about/rules.json"; requested != want {
+		t.Errorf("requested %q, want %q", requested, want)
+	}
+	want := []Rule{
+		{ShortName: "Go related", Description: "Posts must be about Go.", Kind: "link"},
+		{ShortName: "Be civil", Kind: "all"},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %d rules, want %d: %+v", len(rules), len(want), rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+}
+
+func TestExtractSubredditRulesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"not found", http.StatusNotFound, `{"message": "Not Found", "error": 404}`, ErrSubredditNotFound},
+		{"banned", http.StatusNotFound, `{"reason": "banned", "message": "Not Found", "error": 404}`, ErrSubredditNotFound},
+		{"private", http.StatusForbidden, `{"reason": "private", "message": "Forbidden", "error": 403}`, ErrSubredditPrivate},
+		{"login required", http.StatusForbidden, `{"message": "Forbidden", "error": 403}`, ErrLoginRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return cannedResponse(req, tt.status, tt.body), nil
+			})}
+			e := mustNewExtractor(WithHTTPClient(client))
+
+			if _, err := e.ExtractSubredditRules(context.Background(), "somesub"); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractSubredditFlairs(t *testing.T) {
+	var requested []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		if req.URL.Path == "/r/golang/api/link_flair_v2.json" {
+			return cannedResponse(req, http.StatusNotFound, `{"message": "Not Found", "error": 404}`), nil
+		}
+		return cannedResponse(req, http.StatusOK, `[
+			{"id": "f1", "text": "Discussion", "text_editable": false, "mod_only": false,
+			 "background_color": "#0079d3", "text_color": "light", "type": "text"},
+			{"id": "f2", "text": "Announcement", "text_editable": true, "mod_only": true,
+			 "background_color": "", "text_color": "dark", "type": "text"}
+		]`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	flairs, err := e.ExtractSubredditFlairs(context.Background(), "golang")
+	if err != nil {
+		t.Fatalf("ExtractSubredditFlairs failed: %v", err)
+	}
+	wantURLs := []string{
+		"https://www.reddit.com/r/golang/api/link_flair_v2.json",
+		"https://www.reddit.com/r/golang/api/link_flair.json",
+	}
+	if len(requested) != len(wantURLs) || requested[0] != wantURLs[0] || requested[1] != wantURLs[1] {
+		t.Errorf("requested %q, want %q", requested, wantURLs)
+	}
+	want := []Flair{
+		{ID: "f1", Text: "Discussion", BackgroundColor: "#0079d3", TextColor: "light"},
+		{ID: "f2", Text: "Announcement", TextEditable: true, ModOnly: true, TextColor: "dark"},
+	}
+	if len(flairs) != len(want) {
+		t.Fatalf("got %d flairs, want %d: %+v", len(flairs), len(want), flairs)
+	}
+	for i := range want {
+		if flairs[i] != want[i] {
+			t.Errorf("flair %d = %+v, want %+v", i, flairs[i], want[i])
+		}
+	}
+}
+
+func TestExtractSubredditFlairsLoginRequired(t *testing.T) {
+	var calls int
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return cannedResponse(req, http.StatusForbidden, `{"message": "Forbidden", "error": 403}`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	if _, err := e.ExtractSubredditFlairs(context.Background(), "golang"); !errors.Is(err, ErrLoginRequired) {
+		t.Fatalf("err = %v, want ErrLoginRequired", err)
+	}
+	if calls != 1 {
+		t.Errorf("requests = %d, want 1 without falling back", calls)
+	}
+}
+
+func TestExtractSubredditRulesInvalidName(t *testing.T) {
+	e := mustNewExtractor()
+	for _, name := range []string{"", "../etc", "has space"} {
+		var verr ValidationError
+		if _, err := e.ExtractSubredditRules(context.Background(), name); !errors.As(err, &verr) {
+			t.Errorf("ExtractSubredditRules(%q) err = %v, want ValidationError", name, err)
+		}
+		if _, err := e.ExtractSubredditFlairs(context.Background(), name); !errors.As(err, &verr) {
+			t.Errorf("ExtractSubredditFlairs(%q) err = %v, want ValidationError", name, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package extractor
+
+import "sync"
+
+// SeenSet records post IDs already handed to the caller. Implementations
+// backed by shared storage let a crawler skip posts across restarts, and
+// must be safe for concurrent use if the Extractor is shared.
+type SeenSet interface {
+	Has(id string) bool
+	Add(id string)
+}
+
+// MemorySeenSet is an in-memory SeenSet. One made by NewMemorySeenSet grows
+// without bound, so long running pollers should replace it periodically or
+// use NewLimitedSeenSet.
+type MemorySeenSet struct {
+	mu  sync.RWMutex
+	ids map[string]struct{}
+	// order lists the IDs oldest first when the set is limited.
+	order []string
+	limit int
+}
+
+// NewMemorySeenSet returns an empty MemorySeenSet.
+func NewMemorySeenSet() *MemorySeenSet {
+	return &MemorySeenSet{ids: make(map[string]struct{})}
+}
+
+// NewLimitedSeenSet returns an empty MemorySeenSet that remembers at most
+// limit IDs, forgetting the oldest added first. That suits pollers of a
+// listing such as "new", where a post that has aged out of the last limit
+// seen ones does not come back. limit <= 0 means no limit.
+func NewLimitedSeenSet(limit int) *MemorySeenSet {
+	return &MemorySeenSet{ids: make(map[string]struct{}), limit: limit}
+}
+
+// Has reports whether id has been added.
+func (s *MemorySeenSet) Has(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.ids[id]
+	return ok
+}
+
+// Add records id.
+func (s *MemorySeenSet) Add(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[id]; ok {
+		return
+	}
+	s.ids[id] = struct{}{}
+	if s.limit <= 0 {
+		return
+	}
+	s.order = append(s.order, id)
+	if len(s.order) > s.limit {
+		delete(s.ids, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// Len returns the number of recorded IDs.
+func (s *MemorySeenSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.ids)
+}
+
+// postKey identifies a listing post, falling back to its canonical link for
+// the rare post without an ID.
+func postKey(post SubredditPost) string {
+	if post.ID != "" {
+		return post.ID
+	}
+	if canonical, err := CanonicalizeRedditURL(post.PostLink); err == nil {
+		return canonical
+	}
+	return post.PostLink
+}
+
+// filterSeen drops posts already in set and records the rest, returning the
+// kept posts and the number skipped.
+func filterSeen(posts []SubredditPost, set SeenSet) ([]SubredditPost, int) {
+	kept := posts[:0]
+	skipped := 0
+	for _, post := range posts {
+		key := postKey(post)
+		if set.Has(key) {
+			skipped++
+			continue
+		}
+		set.Add(key)
+		kept = append(kept, post)
+	}
+	return kept, skipped
+}
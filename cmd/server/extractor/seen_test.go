@@ -0,0 +1,59 @@
+package extractor
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSkipSeen(t *testing.T) {
+	e := fixtureExtractor(completeListingFixture)
+	seen := NewMemorySeenSet()
+	seen.Add("bbb")
+
+	first, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "new", "", 0, "", SkipSeen(seen))
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if len(first.Posts) != 2 || first.Posts[0].ID != "aaa" || first.Posts[1].ID != "ccc" {
+		t.Fatalf("unexpected posts: %+v", first.Posts)
+	}
+	if first.SeenSkipped != 1 {
+		t.Errorf("seen skipped = %d, want 1", first.SeenSkipped)
+	}
+	if seen.Len() != 3 {
+		t.Errorf("seen set holds %d ids, want 3", seen.Len())
+	}
+
+	second, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "new", "", 0, "", SkipSeen(seen))
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if len(second.Posts) != 0 || second.SeenSkipped != 3 {
+		t.Errorf("expected every post to be skipped, got %+v", second)
+	}
+}
+
+func TestWithoutSkipSeen(t *testing.T) {
+	e := fixtureExtractor(completeListingFixture)
+	for i := 0; i < 2; i++ {
+		resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+		if err != nil {
+			t.Fatalf("ExtractSubredditPosts failed: %v", err)
+		}
+		if len(resp.Posts) != 3 || resp.SeenSkipped != 0 {
+			t.Fatalf("call %d: unexpected response %+v", i, resp)
+		}
+	}
+}
+
+func TestLimitedSeenSet(t *testing.T) {
+	seen := NewLimitedSeenSet(2)
+	seen.Add("a")
+	seen.Add("b")
+	seen.Add("a")
+	seen.Add("c")
+	if seen.Len() != 2 || seen.Has("a") || !seen.Has("b") || !seen.Has("c") {
+		t.Errorf("len = %d, has a/b/c = %v/%v/%v, want only the two latest kept",
+			seen.Len(), seen.Has("a"), seen.Has("b"), seen.Has("c"))
+	}
+}
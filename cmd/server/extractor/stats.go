@@ -0,0 +1,40 @@
+package extractor
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ExtractStats accumulates transfer metrics for the extractions run with a
+// context returned by WithStats. It is safe for concurrent use, so one
+// ExtractStats can cover a whole batch or paginated fetch.
+type ExtractStats struct {
+	requests  atomic.Int64
+	bytesRead atomic.Int64
+}
+
+// Requests returns the number of HTTP requests sent to Reddit, including
+// failed ones.
+func (s *ExtractStats) Requests() int64 {
+	return s.requests.Load()
+}
+
+// BytesRead returns the number of response body bytes read.
+func (s *ExtractStats) BytesRead() int64 {
+	return s.bytesRead.Load()
+}
+
+type statsKey struct{}
+
+// WithStats returns a context that records transfer metrics into the
+// returned ExtractStats for every extraction it is passed to. Extractions
+// without such a context record nothing.
+func WithStats(ctx context.Context) (context.Context, *ExtractStats) {
+	stats := &ExtractStats{}
+	return context.WithValue(ctx, statsKey{}, stats), stats
+}
+
+func statsFromContext(ctx context.Context) *ExtractStats {
+	stats, _ := ctx.Value(statsKey{}).(*ExtractStats)
+	return stats
+}
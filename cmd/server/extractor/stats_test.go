@@ -0,0 +1,31 @@
+package extractor
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithStats(t *testing.T) {
+	e := fixtureExtractor(postFixture)
+
+	ctx, stats := WithStats(context.Background())
+	if _, err := e.ExtractRedditPost(ctx, testPostURL); err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	if _, err := e.ExtractRedditPost(ctx, testPostURL); err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+
+	if stats.Requests() != 2 {
+		t.Errorf("requests = %d, want 2", stats.Requests())
+	}
+	if want := int64(2 * len(postFixture)); stats.BytesRead() != want {
+		t.Errorf("bytes read = %d, want %d", stats.BytesRead(), want)
+	}
+}
+
+func TestWithoutStats(t *testing.T) {
+	if stats := statsFromContext(context.Background()); stats != nil {
+		t.Fatalf("expected no stats on a plain context, got %+v", stats)
+	}
+}
@@ -1,14 +1,15 @@
 package extractor
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
+	"slices"
 	"strings"
 	"time"
 )
@@ -16,6 +17,7 @@ import (
 const (
 	defaultSubredditSort  = "hot"
 	defaultSubredditLimit = 20
+	defaultPreviewRunes   = 200
 	maxSubredditLimit     = 100
 )
 
@@ -30,68 +32,129 @@ func (e ValidationError) Error() string {
 
 // SubredditPost represents a single post from a subreddit listing.
 type SubredditPost struct {
-	Title        string   `json:"title"`
-	ImageURLs    []string `json:"image_urls,omitempty"`
-	PostLink     string   `json:"post_link"`
-	Score        int      `json:"score,omitempty"`
-	Comments     int      `json:"comments,omitempty"`
-	ExternalLink string   `json:"external_link,omitempty"`
+	ID        string `json:"id,omitempty"`
+	Title     string `json:"title"`
+	Subreddit string `json:"subreddit,omitempty"`
+	// Author and PublishedTime are formatted like their RedditPost
+	// counterparts.
+	Author        string   `json:"author,omitempty"`
+	PublishedTime string   `json:"published_time,omitempty"`
+	ImageURLs     []string `json:"image_urls,omitempty"`
+	// Images holds the same images as ImageURLs, with their dimensions and
+	// MIME type where Reddit reports them.
+	Images        []ImageInfo `json:"images,omitempty"`
+	PostLink      string      `json:"post_link"`
+	Score         int         `json:"score,omitempty"`
+	Comments      int         `json:"comments,omitempty"`
+	ExternalLink  string      `json:"external_link,omitempty"`
+	Embed         *Embed      `json:"embed,omitempty"`
+	Gildings      *Gildings   `json:"gildings,omitempty"`
+	Distinguished string      `json:"distinguished,omitempty"`
+	Stickied      bool        `json:"stickied,omitempty"`
+	IsSelf        bool        `json:"is_self,omitempty"`
+	Flair         string      `json:"flair,omitempty"`
+	Crossposts    int         `json:"crossposts,omitempty"`
+	ViewCount     int         `json:"view_count,omitempty"`
+	// ContentPreview is the start of a self post's text, see
+	// ContentPreview. The full text is only returned by post extraction.
+	ContentPreview string `json:"content_preview,omitempty"`
 }
 
 // SubredditListResponse represents a subreddit listing response.
+//
+// Partial is set when the listing body was cut short (for example by a
+// dropped connection) and Posts only holds the posts received completely
+// before the cut; PartialError describes what went wrong.
 type SubredditListResponse struct {
-	Posts     []SubredditPost `json:"posts"`
-	NextAfter string          `json:"next_after,omitempty"`
-	HasMore   bool            `json:"has_more"`
+	// Source is SourceJSON or SourceRSS, depending on which of them the
+	// posts came from.
+	Source       string          `json:"source"`
+	Posts        []SubredditPost `json:"posts"`
+	NextAfter    string          `json:"next_after,omitempty"`
+	HasMore      bool            `json:"has_more"`
+	Partial      bool            `json:"partial,omitempty"`
+	PartialError string          `json:"partial_error,omitempty"`
+	// SeenSkipped counts posts dropped by SkipSeen.
+	SeenSkipped int `json:"seen_skipped,omitempty"`
+	// FieldCoverage is set with ReportFieldCoverage. It maps "posts" to the
+	// number of posts parsed from the listing, before WithPostFilter and
+	// SkipSeen, and each of "title", "image", "score" and "external_link"
+	// to how many of them had that field populated. A count falling well
+	// below "posts" hints that Reddit changed its JSON.
+	FieldCoverage map[string]int `json:"field_coverage,omitempty"`
 }
 
 type redditListingResponse struct {
 	Kind string `json:"kind"`
 	Data struct {
-		After    string `json:"after"`
-		Children []struct {
-			Kind string                `json:"kind"`
-			Data redditListingPostData `json:"data"`
-		} `json:"children"`
+		After    string               `json:"after"`
+		Children []redditListingChild `json:"children"`
 	} `json:"data"`
 }
 
+type redditListingChild struct {
+	Kind string                `json:"kind"`
+	Data redditListingPostData `json:"data"`
+}
+
 type redditListingPostData struct {
-	Title             string `json:"title"`
-	Author            string `json:"author"`
-	Score             int    `json:"score"`
-	NumComments       int    `json:"num_comments"`
-	Selftext          string `json:"selftext"`
-	Permalink         string `json:"permalink"`
-	URL               string `json:"url"`
-	IsSelf            bool   `json:"is_self"`
-	PostHint          string `json:"post_hint"`
-	IsGallery         bool   `json:"is_gallery"`
-	IsVideo           bool   `json:"is_video"`
-	RemovedByCategory string `json:"removed_by_category"`
-	Preview           struct {
+	ID                    string       `json:"id"`
+	Name                  string       `json:"name"`
+	Title                 string       `json:"title"`
+	Author                string       `json:"author"`
+	CreatedUTC            float64      `json:"created_utc"`
+	Subreddit             string       `json:"subreddit"`
+	SubredditNamePrefixed string       `json:"subreddit_name_prefixed"`
+	Score                 int          `json:"score"`
+	NumComments           int          `json:"num_comments"`
+	Selftext              string       `json:"selftext"`
+	Permalink             string       `json:"permalink"`
+	URL                   string       `json:"url"`
+	IsSelf                bool         `json:"is_self"`
+	PostHint              string       `json:"post_hint"`
+	IsGallery             bool         `json:"is_gallery"`
+	IsVideo               bool         `json:"is_video"`
+	RemovedByCategory     string       `json:"removed_by_category"`
+	Media                 *redditMedia `json:"media"`
+	SecureMedia           *redditMedia `json:"secure_media"`
+	Distinguished         string       `json:"distinguished"`
+	Stickied              bool         `json:"stickied"`
+	LinkFlairText         string       `json:"link_flair_text"`
+	NumCrossposts         int          `json:"num_crossposts"`
+	ViewCount             int          `json:"view_count"` // usually null
+	Preview               struct {
 		Images []struct {
-			Source struct {
-				URL string `json:"url"`
-			} `json:"source"`
+			Source redditImageSource `json:"source"`
 		} `json:"images"`
 	} `json:"preview"`
-	MediaMetadata map[string]struct {
-		Status string `json:"status"`
-		E      string `json:"e"`
-		M      string `json:"m"`
-		S      struct {
-			U string `json:"u"`
-		} `json:"s"`
-	} `json:"media_metadata"`
+	GalleryData   *redditGalleryData         `json:"gallery_data"`
+	MediaMetadata map[string]redditMediaItem `json:"media_metadata"`
+	Gildings      redditGildings             `json:"gildings"`
+}
+
+// ExtractSubredditPosts fetches a subreddit listing using the default Extractor.
+func ExtractSubredditPosts(ctx context.Context, subredditURL, sort, timeRange string, limit int, after string, opts ...ExtractOption) (*SubredditListResponse, error) {
+	return defaultExtractor.ExtractSubredditPosts(ctx, subredditURL, sort, timeRange, limit, after, opts...)
 }
 
 // ExtractSubredditPosts fetches a subreddit listing using Reddit JSON API.
-func ExtractSubredditPosts(ctx context.Context, subredditURL, sort, timeRange string, limit int, after string) (*SubredditListResponse, error) {
+func (e *Extractor) ExtractSubredditPosts(ctx context.Context, subredditURL, sort, timeRange string, limit int, after string, opts ...ExtractOption) (result *SubredditListResponse, err error) {
+	o := applyExtractOptions(opts)
+	ctx, span := e.tracer.Start(ctx, "extractor.ExtractSubredditPosts")
+	span.SetAttribute("reddit.url", subredditURL)
+	span.SetAttribute("reddit.sort", sort)
+	span.SetAttribute("reddit.limit", limit)
+	defer func() {
+		if result != nil {
+			span.SetAttribute("reddit.post_count", len(result.Posts))
+		}
+		endSpan(span, err)
+	}()
+
 	// Initialize logger for stderr output
-	logger := log.New(os.Stderr, "[subreddit] ", log.LstdFlags|log.Lmsgprefix)
+	logger := newLogger(ctx, "subreddit")
 
-	subreddit, err := parseSubredditURL(subredditURL)
+	subreddit, err := e.subredditFromURL(subredditURL)
 	if err != nil {
 		logger.Printf("validation error: url=%s, err=%v", subredditURL, err)
 		return nil, ValidationError{Message: err.Error()}
@@ -102,22 +165,32 @@ func ExtractSubredditPosts(ctx context.Context, subredditURL, sort, timeRange st
 		logger.Printf("invalid sort parameter: sort=%s, subreddit=%s", sort, subreddit)
 		return nil, ValidationError{Message: "invalid sort"}
 	}
+	if e.strictValidation && normalizedSort == "" {
+		logger.Printf("missing sort parameter: subreddit=%s", subreddit)
+		return nil, ValidationError{Message: "sort is required"}
+	}
+	if e.strictValidation && limit == 0 {
+		logger.Printf("missing limit parameter: subreddit=%s", subreddit)
+		return nil, ValidationError{Message: "limit is required"}
+	}
 	if normalizedSort == "" {
-		normalizedSort = defaultSubredditSort
+		normalizedSort = e.defaultSort
 	}
 	if limit == 0 {
-		limit = defaultSubredditLimit
+		limit = e.defaultLimit
 	}
-	if limit < 1 || limit > maxSubredditLimit {
+	if limit < 1 || limit > e.maxLimit {
 		logger.Printf("invalid limit parameter: limit=%d, subreddit=%s", limit, subreddit)
-		return nil, ValidationError{Message: fmt.Sprintf("limit must be between 1 and %d", maxSubredditLimit)}
+		return nil, ValidationError{Message: fmt.Sprintf("limit must be between 1 and %d", e.maxLimit)}
 	}
 	if timeRange != "" && normalizedSort == "top" && !isValidTimeRange(timeRange) {
 		logger.Printf("invalid time_range parameter: time_range=%s, subreddit=%s", timeRange, subreddit)
 		return nil, ValidationError{Message: "invalid time_range"}
 	}
+	if o.imagesOnly && o.selfOnly {
+		return nil, ValidationError{Message: "images_only and self_only are mutually exclusive"}
+	}
 
-	apiURL := fmt.Sprintf("https://www.reddit.com/r/%s/%s.json", subreddit, normalizedSort)
 	query := url.Values{}
 	query.Set("limit", fmt.Sprintf("%d", limit))
 	if after != "" {
@@ -126,49 +199,363 @@ func ExtractSubredditPosts(ctx context.Context, subredditURL, sort, timeRange st
 	if normalizedSort == "top" && timeRange != "" {
 		query.Set("t", timeRange)
 	}
-	apiURL = apiURL + "?" + query.Encode()
+	apiURL := redditAPIURL("json", query, "r", subreddit, normalizedSort)
 
 	logger.Printf("fetching: subreddit=%s, sort=%s, limit=%d, after=%s", subreddit, normalizedSort, limit, after)
 
+	listing, partialErr, err := e.fetchListing(ctx, apiURL, subreddit, logger)
+	for attempt := 0; err == nil && listing != nil && len(listing.Data.Children) == 0 && attempt < e.emptyListingRetries; attempt++ {
+		logger.Printf("empty listing, retrying: subreddit=%s, attempt=%d, delay=%s", subreddit, attempt+1, e.emptyListingDelay)
+		if err := e.sleep(ctx, e.emptyListingDelay); err != nil {
+			return nil, err
+		}
+		listing, partialErr, err = e.fetchListing(ctx, apiURL, subreddit, logger)
+	}
+	var blocked BlockedError
+	if e.fallbackToRSS && (errors.Is(err, errListingForbidden) || errors.As(err, &blocked)) {
+		logger.Printf("json api refused, falling back to rss: subreddit=%s, err=%v", subreddit, err)
+		posts, rssErr := e.fetchRSSPosts(ctx, subreddit, normalizedSort, limit, logger)
+		if rssErr == nil {
+			kept, filteredCount := filterRSSPosts(posts, o)
+			var coverage map[string]int
+			if o.fieldCoverage {
+				coverage = fieldCoverage(kept)
+			}
+			result = e.finishListing(kept, filteredCount, "", o, subreddit, logger)
+			result.Source = SourceRSS
+			result.FieldCoverage = coverage
+			return result, nil
+		}
+		logger.Printf("rss fallback failed: subreddit=%s, err=%v", subreddit, rssErr)
+	}
+	if errors.Is(err, errListingForbidden) {
+		// Short of a working fallback, a 403 means a private or
+		// quarantined subreddit, reported like a missing one.
+		listing, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if listing == nil {
+		return &SubredditListResponse{
+			Posts:   []SubredditPost{},
+			HasMore: false,
+			Source:  SourceJSON,
+		}, nil
+	}
+
+	posts, filteredCount := parseListingPosts(*listing, logger, o)
+	var coverage map[string]int
+	if o.fieldCoverage {
+		coverage = fieldCoverage(posts)
+	}
+	result = e.finishListing(posts, filteredCount, strings.TrimSpace(listing.Data.After), o, subreddit, logger)
+	result.Source = SourceJSON
+	result.FieldCoverage = coverage
+	if partialErr != nil {
+		result.Partial = true
+		result.PartialError = partialErr.Error()
+	}
+	return result, nil
+}
+
+// finishListing applies WithPostFilter and SkipSeen to the parsed posts of a
+// listing page and builds the response.
+func (e *Extractor) finishListing(posts []SubredditPost, filteredCount int, nextAfter string, o extractOptions, subreddit string, logger *log.Logger) *SubredditListResponse {
+	if e.postFilter != nil {
+		kept := posts[:0]
+		for _, post := range posts {
+			if e.postFilter(post) {
+				kept = append(kept, post)
+			} else {
+				filteredCount++
+			}
+		}
+		posts = kept
+	}
+	seenCount := 0
+	if o.seen != nil {
+		posts, seenCount = filterSeen(posts, o.seen)
+	}
+
+	logger.Printf("success: subreddit=%s, returned=%d, filtered=%d, seen=%d, has_more=%v, next_after=%s",
+		subreddit, len(posts), filteredCount, seenCount, nextAfter != "", nextAfter)
+
+	return &SubredditListResponse{
+		Posts:       posts,
+		NextAfter:   nextAfter,
+		HasMore:     nextAfter != "",
+		SeenSkipped: seenCount,
+	}
+}
+
+// errListingForbidden is returned by fetchListing for a 403, which Reddit
+// sends both for private subreddits and for blocked clients.
+var errListingForbidden = errors.New("listing forbidden")
+
+// fetchListing requests the listing at apiURL. A nil listing with a nil
+// error means the subreddit is unavailable (banned or missing), and
+// errListingForbidden that it may be private.
+// When only part of the body could be decoded, the salvaged listing is
+// returned along with the error that cut it short.
+func (e *Extractor) fetchListing(ctx context.Context, apiURL, subreddit string, logger *log.Logger) (listing *redditListingResponse, partialErr error, err error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
 		logger.Printf("request creation failed: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
-	req.Header.Set("User-Agent", apiUserAgent)
+	e.setAPIHeaders(req)
 
-	client := &http.Client{Timeout: 12 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := e.doRequest(req)
 	if err != nil {
 		logger.Printf("request failed: subreddit=%s, err=%v", subreddit, err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		if resp.StatusCode == http.StatusForbidden {
+			logger.Printf("listing forbidden: subreddit=%s, status=%d", subreddit, resp.StatusCode)
+			return nil, nil, errListingForbidden
+		}
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
 			logger.Printf("subreddit unavailable: subreddit=%s, status=%d", subreddit, resp.StatusCode)
-			return &SubredditListResponse{
-				Posts:   []SubredditPost{},
-				HasMore: false,
-			}, nil
+			return nil, nil, nil
 		}
-		logger.Printf("unexpected response: subreddit=%s, status=%d", subreddit, resp.StatusCode)
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		err := unexpectedStatus(ctx, resp)
+		logger.Printf("unexpected response: subreddit=%s, status=%d, err=%v", subreddit, resp.StatusCode, err)
+		return nil, nil, err
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		logger.Printf("body read failed: subreddit=%s, err=%v", subreddit, err)
-		return nil, err
+	// A body cut short mid-stream may still hold complete posts, so only give
+	// up on read errors when nothing arrived or the caller went away.
+	bodyBytes, readErr := readBody(ctx, resp.Body)
+	if readErr != nil && (len(bodyBytes) == 0 || ctx.Err() != nil) {
+		logger.Printf("body read failed: subreddit=%s, err=%v", subreddit, readErr)
+		return nil, nil, readErr
+	}
+	if err := checkJSONResponse(resp, bodyBytes); err != nil {
+		logger.Printf("blocked response: subreddit=%s, status=%d", subreddit, resp.StatusCode)
+		return nil, nil, err
+	}
+	if err := parseUpstreamError(bodyBytes); err != nil {
+		logger.Printf("error object in response: subreddit=%s, err=%v", subreddit, err)
+		return nil, nil, err
+	}
+	recordRawResponse(ctx, bodyBytes)
+
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	default:
 	}
 
+	var decoded redditListingResponse
+	if err := json.Unmarshal(bodyBytes, &decoded); err != nil {
+		partial, ok := decodePartialListing(bodyBytes)
+		if !ok {
+			logger.Printf("json unmarshal failed: subreddit=%s, err=%v", subreddit, err)
+			return nil, nil, err
+		}
+		partialErr = err
+		if readErr != nil {
+			partialErr = readErr
+		}
+		logger.Printf("partial listing salvaged: subreddit=%s, children=%d, err=%v", subreddit, len(partial.Data.Children), partialErr)
+		decoded = partial
+	}
+	return &decoded, partialErr, nil
+}
+
+// fieldCoverage counts how many of posts have each key field populated.
+func fieldCoverage(posts []SubredditPost) map[string]int {
+	coverage := map[string]int{
+		"posts":         len(posts),
+		"title":         0,
+		"image":         0,
+		"score":         0,
+		"external_link": 0,
+	}
+	for _, post := range posts {
+		if post.Title != "" {
+			coverage["title"]++
+		}
+		if len(post.ImageURLs) > 0 {
+			coverage["image"]++
+		}
+		if post.Score != 0 {
+			coverage["score"]++
+		}
+		if post.ExternalLink != "" {
+			coverage["external_link"]++
+		}
+	}
+	return coverage
+}
+
+// decodePartialListing stream-decodes a listing body that failed to parse as
+// a whole, keeping every child decoded completely before the point of
+// failure. It reports false if no child could be recovered.
+func decodePartialListing(body []byte) (redditListingResponse, bool) {
 	var listing redditListingResponse
-	if err := json.Unmarshal(bodyBytes, &listing); err != nil {
-		logger.Printf("json unmarshal failed: subreddit=%s, err=%v", subreddit, err)
+	dec := json.NewDecoder(bytes.NewReader(body))
+
+	// walkObject calls field for each key of the object starting at the
+	// decoder's position and stops at the first error.
+	walkObject := func(field func(key string) error) error {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return fmt.Errorf("expected object")
+		}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			if err := field(key); err != nil {
+				return err
+			}
+		}
+		_, err := dec.Token()
+		return err
+	}
+	skip := func() error {
+		var discard json.RawMessage
+		return dec.Decode(&discard)
+	}
+
+	_ = walkObject(func(key string) error {
+		switch key {
+		case "kind":
+			return dec.Decode(&listing.Kind)
+		case "data":
+			return walkObject(func(key string) error {
+				switch key {
+				case "after":
+					return dec.Decode(&listing.Data.After)
+				case "children":
+					if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+						return fmt.Errorf("expected array")
+					}
+					for dec.More() {
+						var child redditListingChild
+						if err := dec.Decode(&child); err != nil {
+							return err
+						}
+						listing.Data.Children = append(listing.Data.Children, child)
+					}
+					_, err := dec.Token()
+					return err
+				default:
+					return skip()
+				}
+			})
+		default:
+			return skip()
+		}
+	})
+	return listing, len(listing.Data.Children) > 0
+}
+
+// ExtractSubredditPostsN fetches up to n posts using the default Extractor.
+func ExtractSubredditPostsN(ctx context.Context, subredditURL, sort, timeRange string, n int, opts ...ExtractOption) (*SubredditListResponse, error) {
+	return defaultExtractor.ExtractSubredditPostsN(ctx, subredditURL, sort, timeRange, n, opts...)
+}
+
+// ExtractSubredditPostsN pages through a subreddit listing until n unique
+// posts have been collected or the listing runs out. Posts repeated across
+// pages, which happens when the listing shifts between requests, are kept
+// only once, at their first occurrence. opts apply to every page. Source
+// is that of the first page, the result is Partial if any page was, with
+// the first page's PartialError, and FieldCoverage sums that of all pages.
+func (e *Extractor) ExtractSubredditPostsN(ctx context.Context, subredditURL, sort, timeRange string, n int, opts ...ExtractOption) (*SubredditListResponse, error) {
+	if n < 1 {
+		return nil, ValidationError{Message: "n must be at least 1"}
+	}
+
+	result := &SubredditListResponse{Posts: make([]SubredditPost, 0, n)}
+	seen := make(map[string]struct{}, n)
+	after := ""
+	for len(result.Posts) < n {
+		limit := n - len(result.Posts)
+		if limit > e.maxLimit {
+			limit = e.maxLimit
+		}
+		page, err := e.ExtractSubredditPosts(ctx, subredditURL, sort, timeRange, limit, after, opts...)
+		if err != nil {
+			return nil, err
+		}
+		result.SeenSkipped += page.SeenSkipped
+		if result.Source == "" {
+			result.Source = page.Source
+		}
+		if page.Partial && !result.Partial {
+			result.Partial = true
+			result.PartialError = page.PartialError
+		}
+		if page.FieldCoverage != nil {
+			if result.FieldCoverage == nil {
+				result.FieldCoverage = make(map[string]int, len(page.FieldCoverage))
+			}
+			for field, count := range page.FieldCoverage {
+				result.FieldCoverage[field] += count
+			}
+		}
+		for _, post := range page.Posts {
+			key := postKey(post)
+			if _, dup := seen[key]; dup {
+				continue
+			}
+			seen[key] = struct{}{}
+			result.Posts = append(result.Posts, post)
+			if len(result.Posts) == n {
+				break
+			}
+		}
+		result.NextAfter = page.NextAfter
+		result.HasMore = page.HasMore
+		if !page.HasMore || page.NextAfter == after {
+			break
+		}
+		after = page.NextAfter
+	}
+	return result, nil
+}
+
+// ExtractSubredditImages collects subreddit images using the default
+// Extractor.
+func ExtractSubredditImages(ctx context.Context, subredditURL, sort string, maxPosts int, opts ...ExtractOption) ([]string, error) {
+	return defaultExtractor.ExtractSubredditImages(ctx, subredditURL, sort, maxPosts, opts...)
+}
+
+// ExtractSubredditImages returns the image URLs of up to maxPosts posts of a
+// subreddit listing as one flat list, in listing order and with duplicates
+// removed. It pages like ExtractSubredditPostsN, and opts such as ImagesOnly,
+// MaxImages or MinImageSize apply as they do there.
+func (e *Extractor) ExtractSubredditImages(ctx context.Context, subredditURL, sort string, maxPosts int, opts ...ExtractOption) ([]string, error) {
+	resp, err := e.ExtractSubredditPostsN(ctx, subredditURL, sort, "", maxPosts, opts...)
+	if err != nil {
 		return nil, err
 	}
+	var images []string
+	seen := make(map[string]struct{})
+	for _, post := range resp.Posts {
+		for _, imageURL := range post.ImageURLs {
+			if _, dup := seen[imageURL]; dup {
+				continue
+			}
+			seen[imageURL] = struct{}{}
+			images = append(images, imageURL)
+		}
+	}
+	return images, nil
+}
 
+// parseListingPosts converts the t3 children of a listing into SubredditPosts,
+// dropping removed posts and posts without a usable permalink. It returns the
+// posts and the number of posts filtered out as removed or by the options in
+// o, which also shape each post.
+func parseListingPosts(listing redditListingResponse, logger *log.Logger, o extractOptions) ([]SubredditPost, int) {
 	posts := make([]SubredditPost, 0, len(listing.Data.Children))
 	filteredCount := 0
 	for _, child := range listing.Data.Children {
@@ -186,31 +573,88 @@ func ExtractSubredditPosts(ctx context.Context, subredditURL, sort, timeRange st
 			continue
 		}
 
-		images := collectPostImages(data)
+		images := collectPostImages(data, o)
+		if o.dropsPost(images, data.IsSelf) {
+			filteredCount++
+			continue
+		}
 		externalLink := ""
 		if isExternalLinkURL(data.URL) {
 			externalLink = data.URL
 		}
 
 		posts = append(posts, SubredditPost{
-			Title:        data.Title,
-			ImageURLs:    images,
-			PostLink:     postLink,
-			Score:        data.Score,
-			Comments:     data.NumComments,
-			ExternalLink: externalLink,
+			ID:            canonicalPostID(data.ID, data.Name),
+			Title:         data.Title,
+			Subreddit:     listingSubredditName(data),
+			Author:        strings.TrimSpace(data.Author),
+			PublishedTime: formatCreated(data.CreatedUTC),
+			ImageURLs:     imageURLs(images),
+			Images:        images,
+			PostLink:      postLink,
+			Score:         data.Score,
+			Comments:      data.NumComments,
+			ExternalLink:  externalLink,
+			Embed:         buildEmbed(data.SecureMedia, data.Media),
+			Gildings:      buildGildings(data.Gildings),
+			Distinguished: data.Distinguished,
+			Stickied:      data.Stickied,
+			IsSelf:        data.IsSelf,
+			Flair:         strings.TrimSpace(data.LinkFlairText),
+			Crossposts:    data.NumCrossposts,
+			ViewCount:     data.ViewCount,
+
+			ContentPreview: contentPreview(data.Selftext, o.previewRunes),
 		})
 	}
+	return posts, filteredCount
+}
 
-	nextAfter := strings.TrimSpace(listing.Data.After)
-	logger.Printf("success: subreddit=%s, returned=%d, filtered=%d, has_more=%v, next_after=%s",
-		subreddit, len(posts), filteredCount, nextAfter != "", nextAfter)
+// dropsPost reports whether the ImagesOnly, SelfOnly or MinImageSize
+// options in o drop a post with the given images.
+func (o extractOptions) dropsPost(images []ImageInfo, isSelf bool) bool {
+	return o.imagesOnly && len(images) == 0 || o.selfOnly && !isSelf || o.dropSmallImagePosts && len(images) == 0
+}
 
-	return &SubredditListResponse{
-		Posts:     posts,
-		NextAfter: nextAfter,
-		HasMore:   nextAfter != "",
-	}, nil
+// filterRSSPosts applies the post filters of o to posts from the RSS feed,
+// returning the kept posts and how many were dropped. Feed entries carry
+// neither images nor the self post flag, so ImagesOnly and SelfOnly drop
+// every one of them rather than return posts they may not match.
+func filterRSSPosts(posts []SubredditPost, o extractOptions) ([]SubredditPost, int) {
+	kept := posts[:0]
+	for _, post := range posts {
+		if !o.dropsPost(post.Images, post.IsSelf) {
+			kept = append(kept, post)
+		}
+	}
+	return kept, len(posts) - len(kept)
+}
+
+// formatCreated formats a created_utc timestamp, or returns "" for a
+// missing one.
+func formatCreated(created float64) string {
+	if created <= 0 {
+		return ""
+	}
+	return time.Unix(int64(created), 0).Format(timeLayout)
+}
+
+// contentPreview returns selftext cut to at most n runes, or nothing when
+// n <= 0.
+func contentPreview(selftext string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return truncateRunes(strings.TrimSpace(selftext), n, true)
+}
+
+// listingSubredditName returns the subreddit a listing post belongs to,
+// falling back to the prefixed name ("r/golang") when the plain one is absent.
+func listingSubredditName(data redditListingPostData) string {
+	if name := strings.TrimSpace(data.Subreddit); name != "" {
+		return name
+	}
+	return strings.TrimPrefix(strings.TrimSpace(data.SubredditNamePrefixed), "r/")
 }
 
 func normalizeSubredditSort(sort string) string {
@@ -232,15 +676,39 @@ func isValidTimeRange(timeRange string) bool {
 	}
 }
 
-// ValidateSubredditURL returns an error if the URL is empty or not a subreddit URL.
+// ValidateSubredditURL validates rawURL against the default Extractor's
+// allowed hosts.
 func ValidateSubredditURL(rawURL string) error {
-	_, err := parseSubredditURL(rawURL)
+	return defaultExtractor.ValidateSubredditURL(rawURL)
+}
+
+// ValidateSubredditURL returns an error if the URL is empty, not a subreddit
+// URL, or not on one of the Extractor's allowed Reddit hosts.
+func (e *Extractor) ValidateSubredditURL(rawURL string) error {
+	_, err := e.subredditFromURL(rawURL)
 	if err != nil {
 		return ValidationError{Message: err.Error()}
 	}
 	return nil
 }
 
+// subredditFromURL parses the subreddit name out of rawURL and checks the
+// URL's host against the allowed hosts.
+func (e *Extractor) subredditFromURL(rawURL string) (string, error) {
+	subreddit, err := parseSubredditURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url")
+	}
+	if err := e.checkHost(parsed); err != nil {
+		return "", err
+	}
+	return subreddit, nil
+}
+
 func parseSubredditURL(rawURL string) (string, error) {
 	if strings.TrimSpace(rawURL) == "" {
 		return "", fmt.Errorf("url is required")
@@ -254,7 +722,7 @@ func parseSubredditURL(rawURL string) (string, error) {
 	}
 	pathParts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
 	for i := 0; i < len(pathParts)-1; i++ {
-		if pathParts[i] == "r" && pathParts[i+1] != "" {
+		if pathParts[i] == "r" && subredditNameRE.MatchString(pathParts[i+1]) {
 			return pathParts[i+1], nil
 		}
 	}
@@ -266,32 +734,54 @@ func buildRedditPostLink(permalink string) string {
 	if permalink == "" {
 		return ""
 	}
-	// If already a full URL, return as-is
+	// A full URL must point at a Reddit host, and its path must pass the
+	// same checks as a bare permalink
 	if strings.HasPrefix(permalink, "http://") || strings.HasPrefix(permalink, "https://") {
+		u, err := url.Parse(permalink)
+		if err != nil || u.User != nil || u.Port() != "" {
+			return ""
+		}
+		if !slices.Contains(defaultAllowedHosts, strings.ToLower(u.Hostname())) {
+			return ""
+		}
+		if !isPermalinkPath(u.EscapedPath()) {
+			return ""
+		}
 		return permalink
 	}
-	// Validate path format - must start with /r/
-	if !strings.HasPrefix(permalink, "/r/") {
+	if !isPermalinkPath(permalink) {
 		return ""
 	}
+	return "https://www.reddit.com" + permalink
+}
+
+// isPermalinkPath reports whether path is a safe /r/{subreddit}/... path.
+func isPermalinkPath(path string) bool {
+	// Validate path format - must start with /r/
+	if !strings.HasPrefix(path, "/r/") {
+		return false
+	}
+	// Check the decoded path so encoded sequences such as %2e%2e cannot
+	// slip past the traversal and segment checks
+	decoded, err := url.PathUnescape(path)
+	if err != nil {
+		return false
+	}
 	// Ensure no path traversal attempts
-	if strings.Contains(permalink, "..") {
-		return ""
+	if strings.Contains(decoded, "..") {
+		return false
 	}
 	// Validate path segments and ensure reasonable structure
-	parts := strings.Split(strings.Trim(permalink, "/"), "/")
+	parts := strings.Split(strings.Trim(decoded, "/"), "/")
 	if len(parts) < 3 { // At least /r/subreddit should exist
-		return ""
+		return false
 	}
 	// First part must be "r"
 	if parts[0] != "r" {
-		return ""
-	}
-	// Second part should be the subreddit name (non-empty)
-	if parts[1] == "" {
-		return ""
+		return false
 	}
-	return "https://www.reddit.com" + permalink
+	// Second part should be the subreddit name
+	return subredditNameRE.MatchString(parts[1])
 }
 
 func isRemovedPost(title, selftext, removedCategory string) bool {
@@ -310,41 +800,42 @@ func isRemovedPost(title, selftext, removedCategory string) bool {
 	return false
 }
 
-func collectPostImages(data redditListingPostData) []string {
-	var images []string
+// collectPostImages returns the post's images, gallery images first in
+// display order, with the image options in o applied.
+func collectPostImages(data redditListingPostData, o extractOptions) []ImageInfo {
+	var images []ImageInfo
 
 	if data.IsVideo {
 		return images
 	}
 
 	if data.IsGallery && data.MediaMetadata != nil {
-		for _, media := range data.MediaMetadata {
-			if media.Status == "valid" && strings.EqualFold(media.E, "Image") && media.S.U != "" {
-				imageURL := strings.ReplaceAll(media.S.U, "&amp;", "&")
-				images = append(images, imageURL)
-			}
-		}
+		images = galleryImageInfo(data.GalleryData, data.MediaMetadata, o.preferDurableImages)
 		if len(images) > 0 {
-			return images
+			return o.finishImageInfo(images)
 		}
 	}
 
 	if data.PostHint == "image" || isRedditImageURL(data.URL) {
-		if strings.TrimSpace(data.URL) != "" {
-			images = append(images, data.URL)
+		if isValidImageURL(data.URL) {
+			// The preview of an image post is the same image.
+			img := ImageInfo{URL: data.URL}
+			if len(data.Preview.Images) > 0 {
+				img.Width, img.Height = data.Preview.Images[0].Source.Width, data.Preview.Images[0].Source.Height
+			}
+			images = append(images, img)
 		}
 	}
 
 	if len(images) == 0 && len(data.Preview.Images) > 0 {
 		for _, img := range data.Preview.Images {
-			if img.Source.URL == "" {
-				continue
+			if imageURL := strings.ReplaceAll(img.Source.URL, "&amp;", "&"); isValidImageURL(imageURL) {
+				images = append(images, ImageInfo{URL: imageURL, Width: img.Source.Width, Height: img.Source.Height})
 			}
-			images = append(images, strings.ReplaceAll(img.Source.URL, "&amp;", "&"))
 		}
 	}
 
-	return images
+	return o.finishImageInfo(images)
 }
 
 func isExternalLinkURL(rawURL string) bool {
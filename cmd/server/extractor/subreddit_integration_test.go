@@ -258,7 +258,7 @@ func TestExtractSubredditPosts_Integration_Validation(t *testing.T) {
 					_, ok := err.(ValidationError)
 					if !ok {
 						// Check error message contains expected validation keywords
-					 errMsg := err.Error()
+						errMsg := err.Error()
 						if !strings.Contains(errMsg, "invalid") && !strings.Contains(errMsg, "between") && !strings.Contains(errMsg, "required") {
 							t.Errorf("expected ValidationError for %s, got: %v", tc.name, err)
 						}
@@ -280,44 +280,99 @@ func TestExtractSubredditPosts_Integration_Validation(t *testing.T) {
 
 func TestBuildRedditPostLink(t *testing.T) {
 	testCases := []struct {
-		name     string
+		name      string
 		permalink string
-		want     string
+		want      string
 	}{
 		{
-			name:     "valid permalink",
+			name:      "valid permalink",
 			permalink: "/r/golang/comments/abc123/test_post/",
-			want:     "https://www.reddit.com/r/golang/comments/abc123/test_post/",
+			want:      "https://www.reddit.com/r/golang/comments/abc123/test_post/",
 		},
 		{
-			name:     "full URL",
+			name:      "full URL",
 			permalink: "https://www.reddit.com/r/golang/comments/abc123/",
-			want:     "https://www.reddit.com/r/golang/comments/abc123/",
+			want:      "https://www.reddit.com/r/golang/comments/abc123/",
+		},
+		{
+			name:      "full URL on another Reddit host",
+			permalink: "https://old.reddit.com/r/golang/comments/abc123/",
+			want:      "https://old.reddit.com/r/golang/comments/abc123/",
+		},
+		{
+			name:      "full URL on a foreign host",
+			permalink: "https://evil.example/r/golang/comments/abc123/",
+			want:      "",
+		},
+		{
+			name:      "full URL on a lookalike host",
+			permalink: "https://www.reddit.com.evil.example/r/golang/comments/abc123/",
+			want:      "",
+		},
+		{
+			name:      "full URL with credentials",
+			permalink: "https://user@www.reddit.com/r/golang/comments/abc123/",
+			want:      "",
 		},
 		{
-			name:     "empty permalink",
+			name:      "full URL with encoded path traversal",
+			permalink: "https://www.reddit.com/r/golang/%2e%2e/%2e%2e/api/",
+			want:      "",
+		},
+		{
+			name:      "full URL outside /r/",
+			permalink: "https://www.reddit.com/api/v1/me",
+			want:      "",
+		},
+		{
+			name:      "empty permalink",
 			permalink: "",
-			want:     "",
+			want:      "",
 		},
 		{
-			name:     "path traversal attack",
+			name:      "path traversal attack",
 			permalink: "/r/golang/../../etc/passwd",
-			want:     "",
+			want:      "",
+		},
+		{
+			name:      "encoded path traversal",
+			permalink: "/r/golang/%2e%2e/%2e%2e/etc/passwd",
+			want:      "",
+		},
+		{
+			name:      "mixed-case encoded path traversal",
+			permalink: "/r/golang/comments/%2E./%2e%2E/etc",
+			want:      "",
+		},
+		{
+			name:      "encoded slash in subreddit",
+			permalink: "/r/..%2f..%2fapi/comments/abc123/",
+			want:      "",
+		},
+		{
+			name:      "invalid percent-encoding",
+			permalink: "/r/golang/comments/abc123/%zz/",
+			want:      "",
+		},
+		{
+			name:      "encoded title slug",
+			permalink: "/r/golang/comments/abc123/caf%C3%A9/",
+			want:      "https://www.reddit.com/r/golang/comments/abc123/caf%C3%A9/",
 		},
 		{
-			name:     "invalid format - missing /r/",
+			name:      "invalid format - missing /r/",
 			permalink: "/golang/comments/abc123/",
-			want:     "",
+			want:      "",
 		},
 		{
-			name:     "invalid format - too short",
+			name:      "invalid format - too short",
 			permalink: "/r/",
-			want:     "",
+			want:      "",
 		},
 		{
-			name:     "whitespace only",
+			name:      "whitespace only",
 			permalink: "   ",
-			want:     "",
+			want:      "",
 		},
 	}
 
@@ -0,0 +1,660 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func discardLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+func decodeListing(t *testing.T, body string) redditListingResponse {
+	t.Helper()
+	var listing redditListingResponse
+	if err := json.Unmarshal([]byte(body), &listing); err != nil {
+		t.Fatalf("unmarshal listing fixture: %v", err)
+	}
+	return listing
+}
+
+func TestParseListingPostsSubreddit(t *testing.T) {
+	listing := decodeListing(t, `{
+		"kind": "Listing",
+		"data": {
+			"children": [
+				{"kind": "t3", "data": {"title": "a", "subreddit": "golang", "subreddit_name_prefixed": "r/golang", "permalink": "/r/golang/comments/aaa111/a/"}},
+				{"kind": "t3", "data": {"title": "b", "subreddit_name_prefixed": "r/rust", "permalink": "/r/rust/comments/bbb222/b/"}},
+				{"kind": "t3", "data": {"title": "c", "permalink": "/r/python/comments/ccc333/c/"}}
+			]
+		}
+	}`)
+
+	posts, filtered := parseListingPosts(listing, discardLogger(), extractOptions{})
+	if filtered != 0 {
+		t.Fatalf("filtered = %d, want 0", filtered)
+	}
+	want := []string{"golang", "rust", ""}
+	if len(posts) != len(want) {
+		t.Fatalf("got %d posts, want %d", len(posts), len(want))
+	}
+	for i, post := range posts {
+		if post.Subreddit != want[i] {
+			t.Errorf("post %d subreddit = %q, want %q", i, post.Subreddit, want[i])
+		}
+	}
+}
+
+func TestExtractSubredditPostsNDeduplicates(t *testing.T) {
+	pages := map[string]string{
+		"": `{"kind": "Listing", "data": {"after": "t3_bbb", "children": [
+			{"kind": "t3", "data": {"title": "a", "permalink": "/r/golang/comments/aaa/a/"}},
+			{"kind": "t3", "data": {"title": "b", "permalink": "/r/golang/comments/bbb/b/"}}
+		]}}`,
+		"t3_bbb": `{"kind": "Listing", "data": {"after": "t3_ddd", "children": [
+			{"kind": "t3", "data": {"title": "b", "permalink": "/r/golang/comments/bbb/b/"}},
+			{"kind": "t3", "data": {"title": "c", "permalink": "/r/golang/comments/ccc/c/"}}
+		]}}`,
+		"t3_ddd": `{"kind": "Listing", "data": {"children": [
+			{"kind": "t3", "data": {"title": "d", "permalink": "/r/golang/comments/ddd/d/"}}
+		]}}`,
+	}
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, ok := pages[req.URL.Query().Get("after")]
+		if !ok {
+			return cannedResponse(req, http.StatusBadRequest, ""), nil
+		}
+		return cannedResponse(req, http.StatusOK, body), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	resp, err := e.ExtractSubredditPostsN(context.Background(), "https://www.reddit.com/r/golang/", "new", "", 4)
+	if err != nil {
+		t.Fatalf("ExtractSubredditPostsN failed: %v", err)
+	}
+	var titles []string
+	for _, post := range resp.Posts {
+		titles = append(titles, post.Title)
+	}
+	if got, want := strings.Join(titles, ","), "a,b,c,d"; got != want {
+		t.Errorf("titles = %s, want %s", got, want)
+	}
+	if resp.HasMore {
+		t.Error("expected has_more=false after the last page")
+	}
+}
+
+func TestExtractSubredditPostsNCarriesPageFields(t *testing.T) {
+	truncated := completeListingFixture[:strings.Index(completeListingFixture, `"title": "c"`)]
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("after") == "" {
+			return cannedResponse(req, http.StatusOK, truncated), nil
+		}
+		return cannedResponse(req, http.StatusOK, `{"kind": "Listing", "data": {"children": [
+			{"kind": "t3", "data": {"id": "ddd", "title": "d", "score": 3, "permalink": "/r/golang/comments/ddd/d/"}}
+		]}}`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	resp, err := e.ExtractSubredditPostsN(context.Background(), "https://www.reddit.com/r/golang/", "new", "", 5, ReportFieldCoverage())
+	if err != nil {
+		t.Fatalf("ExtractSubredditPostsN failed: %v", err)
+	}
+	if len(resp.Posts) != 3 {
+		t.Fatalf("got %d posts, want 3: %+v", len(resp.Posts), resp.Posts)
+	}
+	if resp.Source != SourceJSON {
+		t.Errorf("source = %q, want %q", resp.Source, SourceJSON)
+	}
+	if !resp.Partial || resp.PartialError == "" {
+		t.Errorf("partial = %v, partial_error = %q, want the first page's truncation", resp.Partial, resp.PartialError)
+	}
+	if resp.FieldCoverage["posts"] != 3 || resp.FieldCoverage["title"] != 3 || resp.FieldCoverage["score"] != 1 {
+		t.Errorf("field coverage = %v, want the pages summed", resp.FieldCoverage)
+	}
+}
+
+func TestParseListingPostsID(t *testing.T) {
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"id": "abc123", "name": "t3_abc123", "title": "a", "permalink": "/r/golang/comments/abc123/a/"}},
+		{"kind": "t3", "data": {"name": "t3_def456", "title": "b", "permalink": "/r/golang/comments/def456/b/"}}
+	]}}`)
+
+	posts, _ := parseListingPosts(listing, discardLogger(), extractOptions{})
+	if len(posts) != 2 || posts[0].ID != "abc123" || posts[1].ID != "def456" {
+		t.Fatalf("unexpected ids: %+v", posts)
+	}
+}
+
+func TestExtractSubredditPostsNDeduplicatesByID(t *testing.T) {
+	pages := map[string]string{
+		"": `{"kind": "Listing", "data": {"after": "t3_aaa", "children": [
+			{"kind": "t3", "data": {"id": "aaa", "title": "a", "permalink": "/r/golang/comments/aaa/a/"}}
+		]}}`,
+		"t3_aaa": `{"kind": "Listing", "data": {"children": [
+			{"kind": "t3", "data": {"id": "aaa", "title": "a", "permalink": "/r/golang/comments/aaa/a_renamed/"}},
+			{"kind": "t3", "data": {"id": "bbb", "title": "b", "permalink": "/r/golang/comments/bbb/b/"}}
+		]}}`,
+	}
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return cannedResponse(req, http.StatusOK, pages[req.URL.Query().Get("after")]), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	resp, err := e.ExtractSubredditPostsN(context.Background(), "https://www.reddit.com/r/golang/", "new", "", 5)
+	if err != nil {
+		t.Fatalf("ExtractSubredditPostsN failed: %v", err)
+	}
+	if len(resp.Posts) != 2 || resp.Posts[0].ID != "aaa" || resp.Posts[1].ID != "bbb" {
+		t.Fatalf("unexpected posts: %+v", resp.Posts)
+	}
+}
+
+func TestParseListingPostsDistinguished(t *testing.T) {
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"title": "Weekly thread", "distinguished": "moderator", "stickied": true, "permalink": "/r/golang/comments/aaa/weekly/"}},
+		{"kind": "t3", "data": {"title": "b", "distinguished": null, "permalink": "/r/golang/comments/bbb/b/"}}
+	]}}`)
+
+	posts, _ := parseListingPosts(listing, discardLogger(), extractOptions{})
+	if posts[0].Distinguished != "moderator" || !posts[0].Stickied {
+		t.Errorf("unexpected first post: %+v", posts[0])
+	}
+	if posts[1].Distinguished != "" || posts[1].Stickied {
+		t.Errorf("unexpected second post: %+v", posts[1])
+	}
+}
+
+func TestValidateSubredditURLAllowedHosts(t *testing.T) {
+	e := mustNewExtractor(WithAllowedHosts([]string{"www.reddit.com"}))
+	if err := e.ValidateSubredditURL("https://www.reddit.com/r/golang/"); err != nil {
+		t.Errorf("unexpected error for allowed host: %v", err)
+	}
+	err := e.ValidateSubredditURL("https://old.reddit.com/r/golang/")
+	if _, ok := err.(ValidationError); !ok {
+		t.Errorf("err = %v, want ValidationError for a host outside the allowlist", err)
+	}
+}
+
+const completeListingFixture = `{"kind": "Listing", "data": {"after": "t3_ccc", "children": [
+	{"kind": "t3", "data": {"id": "aaa", "title": "a", "permalink": "/r/golang/comments/aaa/a/"}},
+	{"kind": "t3", "data": {"id": "bbb", "title": "b", "permalink": "/r/golang/comments/bbb/b/"}},
+	{"kind": "t3", "data": {"id": "ccc", "title": "c", "permalink": "/r/golang/comments/ccc/c/"}}
+]}}`
+
+func TestExtractSubredditPostsTruncatedListing(t *testing.T) {
+	// Cut the body in the middle of the third child.
+	truncated := completeListingFixture[:strings.Index(completeListingFixture, `"title": "c"`)]
+	e := fixtureExtractor(truncated)
+
+	resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if !resp.Partial || resp.PartialError == "" {
+		t.Errorf("expected a partial result with an error, got %+v", resp)
+	}
+	if len(resp.Posts) != 2 || resp.Posts[0].ID != "aaa" || resp.Posts[1].ID != "bbb" {
+		t.Fatalf("unexpected posts: %+v", resp.Posts)
+	}
+	if resp.NextAfter != "t3_ccc" {
+		t.Errorf("next_after = %q, want t3_ccc", resp.NextAfter)
+	}
+}
+
+func TestExtractSubredditPostsCompleteListingNotPartial(t *testing.T) {
+	resp, err := fixtureExtractor(completeListingFixture).ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if resp.Partial || len(resp.Posts) != 3 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestExtractSubredditPostsTruncatedBeforeAnyChild(t *testing.T) {
+	e := fixtureExtractor(`{"kind": "Listing", "data": {"after": "t3_x", "children": [{"kind": "t3", "da`)
+	if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, ""); err == nil {
+		t.Fatal("expected an error when nothing could be salvaged")
+	}
+}
+
+func TestParseListingPostsImagesOnly(t *testing.T) {
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"id": "img", "title": "image", "permalink": "/r/golang/comments/img/image/", "url": "https://i.redd.it/a.jpg"}},
+		{"kind": "t3", "data": {"id": "txt", "title": "text", "permalink": "/r/golang/comments/txt/text/", "is_self": true}},
+		{"kind": "t3", "data": {"id": "del", "title": "[deleted]", "permalink": "/r/golang/comments/del/x/"}}
+	]}}`)
+
+	posts, filtered := parseListingPosts(listing, discardLogger(), extractOptions{imagesOnly: true})
+	if len(posts) != 1 || posts[0].ID != "img" {
+		t.Fatalf("unexpected posts: %+v", posts)
+	}
+	if filtered != 2 {
+		t.Errorf("filtered = %d, want 2", filtered)
+	}
+}
+
+const mixedListingFixture = `{"kind": "Listing", "data": {"children": [
+	{"kind": "t3", "data": {"id": "img", "title": "image", "permalink": "/r/golang/comments/img/image/", "url": "https://i.redd.it/a.jpg"}},
+	{"kind": "t3", "data": {"id": "txt", "title": "text", "permalink": "/r/golang/comments/txt/text/", "is_self": true}},
+	{"kind": "t3", "data": {"id": "lnk", "title": "link", "permalink": "/r/golang/comments/lnk/link/", "url": "https://go.dev/blog"}}
+]}}`
+
+func TestExtractSubredditPostsContentFilters(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    []ExtractOption
+		want    []string
+		wantErr bool
+	}{
+		{name: "no filter", want: []string{"img", "txt", "lnk"}},
+		{name: "images only", opts: []ExtractOption{ImagesOnly()}, want: []string{"img"}},
+		{name: "self only", opts: []ExtractOption{SelfOnly()}, want: []string{"txt"}},
+		{name: "self only with max images", opts: []ExtractOption{SelfOnly(), MaxImages(1)}, want: []string{"txt"}},
+		{name: "both", opts: []ExtractOption{ImagesOnly(), SelfOnly()}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := fixtureExtractor(mixedListingFixture).ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "", tc.opts...)
+			if tc.wantErr {
+				var validationErr ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("err = %v, want ValidationError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractSubredditPosts failed: %v", err)
+			}
+			var got []string
+			for _, post := range resp.Posts {
+				got = append(got, post.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("posts = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseListingPostsIsSelf(t *testing.T) {
+	posts, _ := parseListingPosts(decodeListing(t, mixedListingFixture), discardLogger(), extractOptions{})
+	if posts[0].IsSelf || !posts[1].IsSelf {
+		t.Errorf("unexpected is_self values: %+v", posts)
+	}
+}
+
+func TestWithPostFilter(t *testing.T) {
+	body := `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"id": "aaa", "title": "a", "permalink": "/r/golang/comments/aaa/a/", "link_flair_text": "Discussion"}},
+		{"kind": "t3", "data": {"id": "bbb", "title": "b", "permalink": "/r/golang/comments/bbb/b/", "link_flair_text": "show & tell "}},
+		{"kind": "t3", "data": {"id": "ccc", "title": "[removed]", "permalink": "/r/golang/comments/ccc/c/", "link_flair_text": "show & tell"}},
+		{"kind": "t3", "data": {"id": "ddd", "title": "d", "permalink": "/r/golang/comments/ddd/d/"}}
+	]}}`
+	var calls int
+	e := fixtureExtractor(body, WithPostFilter(func(post SubredditPost) bool {
+		calls++
+		return post.Flair == "show & tell"
+	}))
+
+	resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if len(resp.Posts) != 1 || resp.Posts[0].ID != "bbb" {
+		t.Fatalf("unexpected posts: %+v", resp.Posts)
+	}
+	// The removed post is dropped before the custom filter sees it.
+	if calls != 3 {
+		t.Errorf("filter called %d times, want 3", calls)
+	}
+}
+
+// listingSequence answers the i-th listing request with bodies[i], repeating
+// the last body once they run out.
+func listingSequence(calls *atomic.Int64, bodies ...string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		i := int(calls.Add(1) - 1)
+		if i >= len(bodies) {
+			i = len(bodies) - 1
+		}
+		return cannedResponse(req, http.StatusOK, bodies[i]), nil
+	})}
+}
+
+const emptyListingFixture = `{"kind": "Listing", "data": {"after": null, "children": []}}`
+
+func TestEmptyListingRetry(t *testing.T) {
+	var calls atomic.Int64
+	e := mustNewExtractor(
+		WithHTTPClient(listingSequence(&calls, emptyListingFixture, completeListingFixture)),
+		WithEmptyListingRetry(2, time.Millisecond),
+	)
+	resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if len(resp.Posts) != 3 || calls.Load() != 2 {
+		t.Errorf("posts = %d, requests = %d, want 3 and 2", len(resp.Posts), calls.Load())
+	}
+}
+
+func TestEmptyListingRetryGivesUp(t *testing.T) {
+	var calls atomic.Int64
+	e := mustNewExtractor(
+		WithHTTPClient(listingSequence(&calls, emptyListingFixture)),
+		WithEmptyListingRetry(2, time.Millisecond),
+	)
+	resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if len(resp.Posts) != 0 || calls.Load() != 3 {
+		t.Errorf("posts = %d, requests = %d, want 0 and 3", len(resp.Posts), calls.Load())
+	}
+
+	// Without the option an empty listing is returned as is.
+	calls.Store(0)
+	e = mustNewExtractor(WithHTTPClient(listingSequence(&calls, emptyListingFixture)))
+	if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, ""); err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("requests = %d, want 1", calls.Load())
+	}
+}
+
+func TestEmptyListingRetryHonoursContext(t *testing.T) {
+	var calls atomic.Int64
+	e := mustNewExtractor(
+		WithHTTPClient(listingSequence(&calls, emptyListingFixture, completeListingFixture)),
+		WithEmptyListingRetry(1, time.Hour),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := e.ExtractSubredditPosts(ctx, "https://www.reddit.com/r/golang/", "", "", 0, "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCrosspostsAndViewCount(t *testing.T) {
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"title": "a", "permalink": "/r/golang/comments/aaa/a/", "num_crossposts": 4, "view_count": null}},
+		{"kind": "t3", "data": {"title": "b", "permalink": "/r/golang/comments/bbb/b/", "num_crossposts": 0, "view_count": 1200}}
+	]}}`)
+	posts, _ := parseListingPosts(listing, discardLogger(), extractOptions{})
+	if posts[0].Crossposts != 4 || posts[0].ViewCount != 0 || posts[1].ViewCount != 1200 {
+		t.Errorf("unexpected counts: %+v", posts)
+	}
+	b, err := json.Marshal(posts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "view_count") {
+		t.Errorf("null view_count should be omitted: %s", b)
+	}
+
+	post, err := fixtureExtractor(`[
+		{"kind": "Listing", "data": {"children": [
+			{"kind": "t3", "data": {"title": "t", "num_crossposts": 2, "view_count": null}}
+		]}},
+		{"kind": "Listing", "data": {"children": []}}
+	]`).ExtractRedditPost(context.Background(), testPostURL)
+	if err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+	if post.Crossposts != 2 || post.ViewCount != 0 {
+		t.Errorf("crossposts = %d, view count = %d, want 2 and 0", post.Crossposts, post.ViewCount)
+	}
+}
+
+// largeListingFixture returns a listing of n posts mixing text, link and
+// gallery posts.
+func largeListingFixture(n int) string {
+	var b strings.Builder
+	b.WriteString(`{"kind": "Listing", "data": {"after": "t3_last", "children": [`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		switch i % 3 {
+		case 0:
+			fmt.Fprintf(&b, `{"kind": "t3", "data": {"id": "p%d", "title": "text %d", "permalink": "/r/golang/comments/p%d/t/", "is_self": true, "selftext": "some text", "score": %d}}`, i, i, i, i)
+		case 1:
+			fmt.Fprintf(&b, `{"kind": "t3", "data": {"id": "p%d", "title": "link %d", "permalink": "/r/golang/comments/p%d/l/", "url": "https://go.dev/blog/%d", "link_flair_text": "news"}}`, i, i, i, i)
+		default:
+			fmt.Fprintf(&b, `{"kind": "t3", "data": {"id": "p%d", "title": "gallery %d", "permalink": "/r/golang/comments/p%d/g/", "is_gallery": true,
+				"gallery_data": {"items": [{"media_id": "a"}, {"media_id": "b"}]},
+				"media_metadata": {
+					"a": {"status": "valid", "e": "Image", "s": {"u": "https://preview.redd.it/a%d.jpg?a=1&amp;b=2"}},
+					"b": {"status": "valid", "e": "Image", "s": {"u": "https://preview.redd.it/b%d.jpg"}}
+				}}}`, i, i, i, i, i)
+		}
+	}
+	b.WriteString(`]}}`)
+	return b.String()
+}
+
+func BenchmarkExtractSubredditPosts(b *testing.B) {
+	e := fixtureExtractor(largeListingFixture(maxSubredditLimit))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", maxSubredditLimit, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWithSubredditLimits(t *testing.T) {
+	var limits []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		limits = append(limits, req.URL.Query().Get("limit"))
+		return cannedResponse(req, http.StatusOK, completeListingFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithSubredditLimits(10, 25))
+
+	if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, ""); err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if len(limits) != 1 || limits[0] != "10" {
+		t.Errorf("limits = %v, want the configured default 10", limits)
+	}
+
+	_, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 26, "")
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Message != "limit must be between 1 and 25" {
+		t.Errorf("err = %v, want a validation error naming the max of 25", err)
+	}
+
+	if e := mustNewExtractor(WithSubredditLimits(50, 500)); e.maxLimit != maxSubredditLimit || e.defaultLimit != 50 {
+		t.Errorf("max = %d, default = %d, want the max capped at %d", e.maxLimit, e.defaultLimit, maxSubredditLimit)
+	}
+	if e := mustNewExtractor(WithSubredditLimits(50, 25)); e.defaultLimit != 25 {
+		t.Errorf("default = %d, want it capped at the max", e.defaultLimit)
+	}
+}
+
+func TestWithDefaultSort(t *testing.T) {
+	var paths []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.URL.Path)
+		return cannedResponse(req, http.StatusOK, completeListingFixture), nil
+	})}
+	e, err := NewExtractor(WithHTTPClient(client), WithDefaultSort(" New "))
+	if err != nil {
+		t.Fatalf("NewExtractor failed: %v", err)
+	}
+
+	for _, sort := range []string{"", "top"} {
+		if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", sort, "", 0, ""); err != nil {
+			t.Fatalf("ExtractSubredditPosts(%q) failed: %v", sort, err)
+		}
+	}
+	if want := []string{"/r/golang/new.json", "/r/golang/top.json"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+
+	if _, err := NewExtractor(WithDefaultSort("best")); err == nil {
+		t.Error("expected NewExtractor to reject an invalid default sort")
+	}
+	if e := mustNewExtractor(WithDefaultSort("")); e.defaultSort != "hot" {
+		t.Errorf("default sort = %q, want hot", e.defaultSort)
+	}
+}
+
+func TestWithStrictValidation(t *testing.T) {
+	const subredditURL = "https://www.reddit.com/r/golang/"
+	tests := []struct {
+		name    string
+		sort    string
+		limit   int
+		wantErr string
+	}{
+		{"explicit", "new", 10, ""},
+		{"empty sort", "", 10, "sort is required"},
+		{"zero limit", "new", 0, "limit is required"},
+	}
+	for _, strict := range []bool{false, true} {
+		e := fixtureExtractor(completeListingFixture, WithStrictValidation(strict))
+		for _, tt := range tests {
+			_, err := e.ExtractSubredditPosts(context.Background(), subredditURL, tt.sort, "", tt.limit, "")
+			if !strict || tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("strict=%v, %s: unexpected error: %v", strict, tt.name, err)
+				}
+				continue
+			}
+			var validationErr ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Message != tt.wantErr {
+				t.Errorf("strict=%v, %s: err = %v, want a validation error %q", strict, tt.name, err, tt.wantErr)
+			}
+		}
+	}
+}
+
+func TestReportFieldCoverage(t *testing.T) {
+	e := fixtureExtractor(mixedListingFixture)
+	resp, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "", ReportFieldCoverage())
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	want := map[string]int{"posts": 3, "title": 3, "image": 1, "score": 0, "external_link": 1}
+	for key, n := range want {
+		if resp.FieldCoverage[key] != n {
+			t.Errorf("coverage[%s] = %d, want %d", key, resp.FieldCoverage[key], n)
+		}
+	}
+
+	resp, err = e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "")
+	if err != nil {
+		t.Fatalf("ExtractSubredditPosts failed: %v", err)
+	}
+	if resp.FieldCoverage != nil {
+		t.Errorf("coverage = %v, want none by default", resp.FieldCoverage)
+	}
+}
+
+const imagesPage1 = `{"kind": "Listing", "data": {"after": "t3_one", "children": [
+	{"kind": "t3", "data": {"id": "gal", "title": "gallery", "permalink": "/r/pics/comments/gal/g/", "is_gallery": true,
+		"gallery_data": {"items": [{"media_id": "a"}, {"media_id": "b"}]},
+		"media_metadata": {
+			"a": {"status": "valid", "e": "Image", "s": {"u": "https://i.redd.it/a.jpg", "x": 2000, "y": 2000}},
+			"b": {"status": "valid", "e": "Image", "s": {"u": "https://i.redd.it/b.jpg", "x": 100, "y": 100}}
+		}}},
+	{"kind": "t3", "data": {"id": "txt", "title": "text", "permalink": "/r/pics/comments/txt/t/", "is_self": true}},
+	{"kind": "t3", "data": {"id": "one", "title": "one", "permalink": "/r/pics/comments/one/o/", "post_hint": "image",
+		"url": "https://i.redd.it/one.jpg",
+		"preview": {"images": [{"source": {"url": "https://preview.redd.it/one.jpg", "width": 2000, "height": 2000}}]}}}
+]}}`
+
+const imagesPage2 = `{"kind": "Listing", "data": {"after": null, "children": [
+	{"kind": "t3", "data": {"id": "dup", "title": "repost", "permalink": "/r/pics/comments/dup/d/", "post_hint": "image",
+		"url": "https://i.redd.it/a.jpg",
+		"preview": {"images": [{"source": {"url": "https://preview.redd.it/a.jpg", "width": 2000, "height": 2000}}]}}},
+	{"kind": "t3", "data": {"id": "two", "title": "two", "permalink": "/r/pics/comments/two/t/", "post_hint": "image",
+		"url": "https://i.redd.it/two.jpg"}}
+]}}`
+
+func TestExtractSubredditImages(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ExtractOption
+		want []string
+	}{
+		{"all", nil, []string{"https://i.redd.it/a.jpg", "https://i.redd.it/b.jpg", "https://i.redd.it/one.jpg", "https://i.redd.it/two.jpg"}},
+		{"min size", []ExtractOption{MinImageSize(1000, 1000, false)}, []string{"https://i.redd.it/a.jpg", "https://i.redd.it/one.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			e := mustNewExtractor(WithHTTPClient(listingSequence(&calls, imagesPage1, imagesPage2)), WithSubredditLimits(0, 3))
+			images, err := e.ExtractSubredditImages(context.Background(), "https://www.reddit.com/r/pics/", "new", 10, tt.opts...)
+			if err != nil {
+				t.Fatalf("ExtractSubredditImages failed: %v", err)
+			}
+			if !reflect.DeepEqual(images, tt.want) {
+				t.Errorf("images = %v, want %v", images, tt.want)
+			}
+			if calls.Load() != 2 {
+				t.Errorf("got %d requests, want 2 pages", calls.Load())
+			}
+		})
+	}
+}
+
+const previewListingFixture = `{"kind": "Listing", "data": {"children": [
+	{"kind": "t3", "data": {"id": "s1", "title": "short", "permalink": "/r/golang/comments/s1/s/", "is_self": true, "selftext": "  Short body.\n"}},
+	{"kind": "t3", "data": {"id": "s2", "title": "long", "permalink": "/r/golang/comments/s2/l/", "is_self": true, "selftext": "日本語のテキストです"}},
+	{"kind": "t3", "data": {"id": "l1", "title": "link", "permalink": "/r/golang/comments/l1/l/", "url": "https://go.dev/", "selftext": ""}}
+]}}`
+
+func TestContentPreview(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ExtractOption
+		want []string
+	}{
+		{"default", nil, []string{"Short body.", "日本語のテキストです", ""}},
+		{"truncated", []ExtractOption{ContentPreview(5)}, []string{"Shor…", "日本語の…", ""}},
+		{"disabled", []ExtractOption{ContentPreview(0)}, []string{"", "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := fixtureExtractor(previewListingFixture).ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, "", tt.opts...)
+			if err != nil {
+				t.Fatalf("ExtractSubredditPosts failed: %v", err)
+			}
+			for i, post := range resp.Posts {
+				if post.ContentPreview != tt.want[i] {
+					t.Errorf("post %s preview = %q, want %q", post.ID, post.ContentPreview, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListingAuthorAndPublishedTime(t *testing.T) {
+	listing := decodeListing(t, `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"id": "a", "title": "a", "permalink": "/r/golang/comments/a/a/", "author": "gopher", "created_utc": 1717000000.0}},
+		{"kind": "t3", "data": {"id": "b", "title": "b", "permalink": "/r/golang/comments/b/b/"}}
+	]}}`)
+	posts, _ := parseListingPosts(listing, discardLogger(), extractOptions{})
+	if want := time.Unix(1717000000, 0).Format(timeLayout); posts[0].Author != "gopher" || posts[0].PublishedTime != want {
+		t.Errorf("post a: author %q, published %q, want gopher and %q", posts[0].Author, posts[0].PublishedTime, want)
+	}
+	if posts[1].Author != "" || posts[1].PublishedTime != "" {
+		t.Errorf("post b: author %q, published %q, want both empty", posts[1].Author, posts[1].PublishedTime)
+	}
+}
@@ -0,0 +1,26 @@
+package extractor
+
+// ellipsis marks text shortened by truncateRunes.
+const ellipsis = "…"
+
+// truncateRunes shortens s to at most max runes, cutting between runes so
+// the result stays valid UTF-8. If ellipsize is set and s was cut, the last
+// kept rune is replaced by an ellipsis, so the result still fits in max.
+// max <= 0 returns s unchanged.
+func truncateRunes(s string, max int, ellipsize bool) string {
+	if max <= 0 {
+		return s
+	}
+	count, lastStart := 0, 0
+	for i := range s {
+		if count == max {
+			if !ellipsize {
+				return s[:i]
+			}
+			return s[:lastStart] + ellipsis
+		}
+		lastStart = i
+		count++
+	}
+	return s
+}
@@ -0,0 +1,41 @@
+package extractor
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	cases := []struct {
+		s         string
+		max       int
+		ellipsize bool
+		want      string
+	}{
+		{s: "hello", max: 0, want: "hello"},
+		{s: "hello", max: 5, want: "hello"},
+		{s: "hello", max: 3, want: "hel"},
+		{s: "hello", max: 3, ellipsize: true, want: "he…"},
+		{s: "hello", max: 1, ellipsize: true, want: "…"},
+		{s: "", max: 3, ellipsize: true, want: ""},
+		{s: "日本語のテキスト", max: 3, want: "日本語"},
+		{s: "日本語のテキスト", max: 3, ellipsize: true, want: "日本…"},
+		{s: "🐹🐹🐹🐹", max: 2, want: "🐹🐹"},
+		{s: "🐹🐹🐹🐹", max: 2, ellipsize: true, want: "🐹…"},
+		// A flag is two runes; cutting between them is still valid UTF-8.
+		{s: "🇯🇵🇯🇵", max: 3, want: "🇯🇵🇯"},
+		{s: "naïve café", max: 4, want: "naïv"},
+	}
+	for _, tc := range cases {
+		got := truncateRunes(tc.s, tc.max, tc.ellipsize)
+		if got != tc.want {
+			t.Errorf("truncateRunes(%q, %d, %v) = %q, want %q", tc.s, tc.max, tc.ellipsize, got, tc.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateRunes(%q, %d, %v) produced invalid UTF-8", tc.s, tc.max, tc.ellipsize)
+		}
+		if n := utf8.RuneCountInString(got); tc.max > 0 && n > tc.max {
+			t.Errorf("truncateRunes(%q, %d, %v) kept %d runes", tc.s, tc.max, tc.ellipsize, n)
+		}
+	}
+}
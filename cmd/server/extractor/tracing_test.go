@@ -0,0 +1,70 @@
+package extractor
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+type recordedSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &recordedSpan{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *recordedSpan) RecordError(err error)                      { s.err = err }
+func (s *recordedSpan) End()                                       { s.ended = true }
+
+func TestWithTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithTracer(tracer))
+
+	if _, err := e.ExtractRedditPost(context.Background(), "https://www.reddit.com/r/golang/comments/abc123/fixture_post/"); err != nil {
+		t.Fatalf("ExtractRedditPost failed: %v", err)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(tracer.spans))
+	}
+	root, child := tracer.spans[0], tracer.spans[1]
+	if root.name != "extractor.ExtractRedditPost" || !root.ended {
+		t.Errorf("unexpected root span: %+v", root)
+	}
+	if root.attrs["reddit.comment_count"] != 1 {
+		t.Errorf("root comment_count = %v, want 1", root.attrs["reddit.comment_count"])
+	}
+	if child.name != "reddit.http" || child.attrs["http.status_code"] != http.StatusOK || !child.ended {
+		t.Errorf("unexpected child span: %+v", child)
+	}
+}
+
+func TestWithTracerRecordsError(t *testing.T) {
+	tracer := &recordingTracer{}
+	e := mustNewExtractor(WithTracer(tracer))
+
+	if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "bogus", "", 0, ""); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if len(tracer.spans) != 1 || tracer.spans[0].err == nil {
+		t.Fatalf("expected a single span with a recorded error, got %+v", tracer.spans)
+	}
+}
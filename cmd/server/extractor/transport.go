@@ -0,0 +1,148 @@
+package extractor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Connection tuning of the default transport. Extractions hit a handful of
+// Reddit hosts, so keep enough idle connections per host to reuse them
+// across concurrent requests.
+const (
+	defaultMaxIdleConnsPerHost = 10
+	defaultMaxConnsPerHost     = 20
+)
+
+// defaultTransport is shared by Extractors that do not configure their own
+// client or transport, so they also share its connection pool.
+var defaultTransport = newDefaultTransport()
+
+func newDefaultTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	t.MaxConnsPerHost = defaultMaxConnsPerHost
+	t.ForceAttemptHTTP2 = true
+	return t
+}
+
+// send sends req once with the Extractor's client inside a child span. When
+// a concurrency limit is configured, the request holds a slot from the time
+// it is sent until its response body is closed. When a minimum interval is
+// configured, the request then waits for its turn at the interval gate, and
+// then at the shared Governor if there is one, both on the Extractor's
+// clock.
+func (e *Extractor) send(req *http.Request) (*http.Response, error) {
+	release := func() {}
+	if e.sem != nil {
+		if err := e.sem.Acquire(req.Context(), 1); err != nil {
+			return nil, err
+		}
+		release = func() { e.sem.Release(1) }
+	}
+	if e.gate != nil {
+		if err := e.sleep(req.Context(), e.gate.reserve(e.clock.Now())); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	if e.governor != nil {
+		if err := e.sleep(req.Context(), e.governor.reserve(e.clock.Now())); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	if stats := statsFromContext(req.Context()); stats != nil {
+		stats.requests.Add(1)
+	}
+
+	_, span := e.tracer.Start(req.Context(), "reddit.http")
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.String())
+	client := e.httpClient
+	if singleHop(req.Context()) {
+		client = e.hopClient
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+	}
+	endSpan(span, err)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// Warmup sends a HEAD request to Reddit so the first extraction finds a
+// resolved, TLS-established connection waiting in the client's pool. Any
+// response counts as success, since only the connection matters; the
+// request skips retries and the circuit breaker, but waits its turn at the
+// interval gate like any other. The caller bounds it through ctx.
+func (e *Extractor) Warmup(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, redditAPIBase+"/", nil)
+	if err != nil {
+		return err
+	}
+	e.setAPIHeaders(req)
+	resp, err := e.send(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// setAPIHeaders sets the headers sent with every Reddit API request. The
+// user agent is apiUserAgent unless the request context overrides it.
+func (e *Extractor) setAPIHeaders(req *http.Request) {
+	ua := UserAgentFromContext(req.Context())
+	if ua == "" {
+		ua = apiUserAgent
+	}
+	req.Header.Set("User-Agent", ua)
+	if e.acceptLanguage != "" {
+		req.Header.Set("Accept-Language", e.acceptLanguage)
+	}
+}
+
+// releasingBody calls release exactly once when the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// intervalGate spaces consecutive requests at least interval apart. Each
+// caller reserves the next free send time and then sleeps until it
+// arrives.
+type intervalGate struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// reserve takes the next free send time, given the current time now, and
+// returns how long the caller has to wait for it.
+func (g *intervalGate) reserve(now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	slot := g.next
+	if slot.Before(now) {
+		slot = now
+	}
+	g.next = slot.Add(g.interval)
+	return slot.Sub(now)
+}
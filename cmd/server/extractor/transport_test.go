@@ -0,0 +1,246 @@
+package extractor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithMaxConcurrency(t *testing.T) {
+	const limit = 2
+	var inFlight, peak int32
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return cannedResponse(req, http.StatusOK, `{"kind": "Listing", "data": {"children": []}}`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithMaxConcurrency(limit))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, ""); err != nil {
+				t.Errorf("ExtractSubredditPosts failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > limit {
+		t.Fatalf("peak in-flight requests = %d, want <= %d", peak, limit)
+	}
+}
+
+func TestWithMaxConcurrencyHonorsContext(t *testing.T) {
+	e := mustNewExtractor(WithMaxConcurrency(1))
+	if err := e.sem.Acquire(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	defer e.sem.Release(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.reddit.com/r/golang/hot.json", nil)
+	if _, err := e.doRequest(req); err == nil {
+		t.Fatal("expected an error while waiting for a slot")
+	}
+}
+
+func TestWithMinInterval(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	var mu sync.Mutex
+	var sent []time.Time
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		sent = append(sent, time.Now())
+		mu.Unlock()
+		return cannedResponse(req, http.StatusOK, `{"kind": "Listing", "data": {"children": []}}`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithMinInterval(interval))
+
+	for i := 0; i < 2; i++ {
+		if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, ""); err != nil {
+			t.Fatalf("ExtractSubredditPosts failed: %v", err)
+		}
+	}
+
+	if len(sent) != 2 {
+		t.Fatalf("got %d requests, want 2", len(sent))
+	}
+	// Allow for timer granularity on the second wait.
+	if gap := sent[1].Sub(sent[0]); gap < interval-5*time.Millisecond {
+		t.Fatalf("requests %v apart, want at least %v", gap, interval)
+	}
+}
+
+func TestWithMinIntervalUsesClock(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return cannedResponse(req, http.StatusOK, `{"kind": "Listing", "data": {"children": []}}`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithClock(clock), WithMinInterval(time.Minute))
+
+	for i := 0; i < 3; i++ {
+		if _, err := e.ExtractSubredditPosts(context.Background(), "https://www.reddit.com/r/golang/", "", "", 0, ""); err != nil {
+			t.Fatalf("ExtractSubredditPosts failed: %v", err)
+		}
+	}
+	if got, want := clock.Now().Sub(start), 2*time.Minute; got != want {
+		t.Fatalf("clock advanced %v, want %v", got, want)
+	}
+}
+
+func TestIntervalGateHonorsContext(t *testing.T) {
+	e := mustNewExtractor()
+	gate := &intervalGate{interval: time.Hour}
+	if err := e.sleep(context.Background(), gate.reserve(e.clock.Now())); err != nil {
+		t.Fatalf("first wait failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := e.sleep(ctx, gate.reserve(e.clock.Now())); err == nil {
+		t.Fatal("expected the second wait to give up with the context")
+	}
+}
+
+func TestWithTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind": "Listing", "data": {"children": []}}`))
+	}))
+	defer server.Close()
+	proxyURL, _ := url.Parse(server.URL)
+
+	var used int32
+	transport := &http.Transport{Proxy: func(*http.Request) (*url.URL, error) {
+		atomic.AddInt32(&used, 1)
+		return proxyURL, nil
+	}}
+	e := mustNewExtractor(WithTransport(transport))
+
+	// The proxy is plain HTTP, so use an http:// target that it can forward.
+	req, _ := http.NewRequest(http.MethodGet, "http://www.reddit.com/r/golang/hot.json", nil)
+	resp, err := e.doRequest(req)
+	if err != nil {
+		t.Fatalf("doRequest failed: %v", err)
+	}
+	resp.Body.Close()
+	if used == 0 {
+		t.Fatal("custom transport was not used")
+	}
+	if e.httpClient.Timeout != defaultRequestTimeout {
+		t.Errorf("timeout = %v, want the default %v", e.httpClient.Timeout, defaultRequestTimeout)
+	}
+}
+
+func TestWithTransportAndHTTPClientLastWins(t *testing.T) {
+	client := &http.Client{}
+	transport := &http.Transport{}
+
+	if e := mustNewExtractor(WithTransport(transport), WithHTTPClient(client)); e.httpClient != client {
+		t.Error("expected WithHTTPClient to win when given last")
+	}
+	if e := mustNewExtractor(WithHTTPClient(client), WithTransport(transport)); e.httpClient.Transport != transport {
+		t.Error("expected WithTransport to win when given last")
+	}
+}
+
+func TestDefaultTransportTuning(t *testing.T) {
+	e := mustNewExtractor()
+	transport, ok := e.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("default transport is %T", e.httpClient.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != defaultMaxIdleConnsPerHost || transport.MaxConnsPerHost != defaultMaxConnsPerHost || !transport.ForceAttemptHTTP2 {
+		t.Errorf("unexpected default transport settings: %+v", transport)
+	}
+}
+
+func TestWithAcceptLanguage(t *testing.T) {
+	var htmlLang atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		htmlLang.Store(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, "<html><body><h1>From HTML</h1></body></html>")
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	postURL := server.URL + "/r/golang/comments/abc123/fixture_post/"
+
+	for _, lang := range []string{"", "en-US"} {
+		var apiLang atomic.Value
+		client := &http.Client{Transport: apiTransport(func(req *http.Request) (*http.Response, error) {
+			apiLang.Store(req.Header.Get("Accept-Language"))
+			return cannedResponse(req, http.StatusOK, postFixture), nil
+		})}
+		opts := []Option{WithHTTPClient(client), WithAllowedHosts([]string{u.Hostname()})}
+		if lang != "" {
+			opts = append(opts, WithAcceptLanguage(lang))
+		}
+		e := mustNewExtractor(opts...)
+
+		if _, err := e.ExtractRedditPostWithOptions(context.Background(), postURL, APIOnly()); err != nil {
+			t.Fatalf("API extraction failed: %v", err)
+		}
+		if _, err := e.ExtractRedditPostWithOptions(context.Background(), postURL, HTMLOnly()); err != nil {
+			t.Fatalf("HTML extraction failed: %v", err)
+		}
+		if got := apiLang.Load(); got != lang {
+			t.Errorf("API Accept-Language = %q, want %q", got, lang)
+		}
+		if got := htmlLang.Load(); got != lang {
+			t.Errorf("HTML Accept-Language = %q, want %q", got, lang)
+		}
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	var method, requested, ua string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		method, requested, ua = req.Method, req.URL.String(), req.Header.Get("User-Agent")
+		return cannedResponse(req, http.StatusForbidden, ""), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	if err := e.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup failed on a 403, which still warms the connection: %v", err)
+	}
+	if method != http.MethodHead || requested != "https://www.reddit.com/" {
+		t.Errorf("sent %s %s, want HEAD https://www.reddit.com/", method, requested)
+	}
+	if ua != apiUserAgent {
+		t.Errorf("user agent = %q, want %q", ua, apiUserAgent)
+	}
+}
+
+func TestWarmupError(t *testing.T) {
+	var calls int
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, &url.Error{Op: "Head", URL: req.URL.String(), Err: io.ErrUnexpectedEOF}
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithRetry(3))
+
+	if err := e.Warmup(context.Background()); err == nil {
+		t.Fatal("expected the connection error")
+	}
+	if calls != 1 {
+		t.Errorf("requests = %d, want 1 without retries", calls)
+	}
+}
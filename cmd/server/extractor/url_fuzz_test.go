@@ -0,0 +1,73 @@
+package extractor
+
+import (
+	"strings"
+	"testing"
+)
+
+var urlFuzzSeeds = []string{
+	"https://www.reddit.com/r/golang/",
+	"https://www.reddit.com/r/golang/comments/abc123/title/",
+	"https://www.reddit.com/r/golang+rust/",
+	"https://www.reddit.com/r/%2e%2e/hot/",
+	"https://www.reddit.com/r/..%2f..%2fapi/comments/abc/",
+	"https://www.reddit.com/r/golang%3Fx=1/",
+	"https://www.reddit.com/?next=/r/golang/comments/abc/",
+	"https://www.reddit.com/r//comments/abc/",
+	"http://[::1]:80/r/x/",
+	"",
+}
+
+func FuzzParseSubredditURL(f *testing.F) {
+	for _, seed := range urlFuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, rawURL string) {
+		subreddit, err := parseSubredditURL(rawURL)
+		if err != nil {
+			return
+		}
+		if subreddit == "" || strings.Contains(subreddit, "/") || !subredditNameRE.MatchString(subreddit) {
+			t.Fatalf("parseSubredditURL(%q) = %q", rawURL, subreddit)
+		}
+	})
+}
+
+func FuzzValidateRedditURL(f *testing.F) {
+	for _, seed := range urlFuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, rawURL string) {
+		_ = ValidateRedditURL(rawURL)
+		subreddit, postID, ok := parseRedditURL(rawURL)
+		if !ok {
+			return
+		}
+		if subreddit == "" || strings.Contains(subreddit, "/") || !subredditNameRE.MatchString(subreddit) {
+			t.Fatalf("parseRedditURL(%q) subreddit = %q", rawURL, subreddit)
+		}
+		if !postIDRE.MatchString(postID) {
+			t.Fatalf("parseRedditURL(%q) post id = %q", rawURL, postID)
+		}
+	})
+}
+
+func TestParseURLsRejectTraversal(t *testing.T) {
+	for _, rawURL := range []string{
+		"https://www.reddit.com/r/%2e%2e/",
+		"https://www.reddit.com/r/golang%3Fx=1/",
+		"https://www.reddit.com/r/..%2f..%2fapi/",
+	} {
+		if subreddit, err := parseSubredditURL(rawURL); err == nil {
+			t.Errorf("parseSubredditURL(%q) = %q, want error", rawURL, subreddit)
+		}
+	}
+	for _, rawURL := range []string{
+		"https://www.reddit.com/r/%2e%2e/comments/abc/",
+		"https://www.reddit.com/?next=/r/golang/comments/abc/",
+	} {
+		if subreddit, postID, ok := parseRedditURL(rawURL); ok {
+			t.Errorf("parseRedditURL(%q) = %q, %q, want no match", rawURL, subreddit, postID)
+		}
+	}
+}
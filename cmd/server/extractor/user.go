@@ -0,0 +1,109 @@
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+var usernameRE = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+
+// ErrUserNotFound is returned when a Reddit account does not exist.
+var ErrUserNotFound = errors.New("reddit user not found")
+
+// UserAbout is the public profile of a Reddit account. Suspended accounts
+// only report their name; the other fields stay zero.
+type UserAbout struct {
+	Name         string `json:"name"`
+	LinkKarma    int    `json:"link_karma"`
+	CommentKarma int    `json:"comment_karma"`
+	CreatedTime  string `json:"created_time,omitempty"`
+	IsGold       bool   `json:"is_gold"`
+	IsMod        bool   `json:"is_mod"`
+	Suspended    bool   `json:"suspended,omitempty"`
+}
+
+type redditUserAboutResponse struct {
+	Kind string `json:"kind"`
+	Data struct {
+		Name         string  `json:"name"`
+		LinkKarma    int     `json:"link_karma"`
+		CommentKarma int     `json:"comment_karma"`
+		CreatedUTC   float64 `json:"created_utc"`
+		IsGold       bool    `json:"is_gold"`
+		IsMod        bool    `json:"is_mod"`
+		IsSuspended  bool    `json:"is_suspended"`
+	} `json:"data"`
+}
+
+// ExtractUserAbout fetches a user profile using the default Extractor.
+func ExtractUserAbout(ctx context.Context, username string) (*UserAbout, error) {
+	return defaultExtractor.ExtractUserAbout(ctx, username)
+}
+
+// ExtractUserAbout fetches the karma and account flags of a Reddit user.
+func (e *Extractor) ExtractUserAbout(ctx context.Context, username string) (*UserAbout, error) {
+	if !usernameRE.MatchString(username) {
+		return nil, ValidationError{Message: "invalid username"}
+	}
+
+	apiURL := redditAPIURL("json", nil, "user", username, "about")
+	var about redditUserAboutResponse
+	status, err := e.fetchJSON(ctx, apiURL, &about)
+	if status == http.StatusNotFound {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := about.Data
+	user := &UserAbout{
+		Name:         data.Name,
+		LinkKarma:    data.LinkKarma,
+		CommentKarma: data.CommentKarma,
+		IsGold:       data.IsGold,
+		IsMod:        data.IsMod,
+		Suspended:    data.IsSuspended,
+	}
+	if user.Name == "" {
+		user.Name = username
+	}
+	if data.CreatedUTC > 0 {
+		user.CreatedTime = time.Unix(int64(data.CreatedUTC), 0).Format(timeLayout)
+	}
+	return user, nil
+}
+
+// fetchJSON GETs apiURL and decodes a 200 JSON response into v. It returns
+// the response status alongside any error so callers can map specific
+// statuses to their own errors; for other statuses the error is the
+// UpstreamError in the body, if Reddit sent one.
+func (e *Extractor) fetchJSON(ctx context.Context, apiURL string, v interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	e.setAPIHeaders(req)
+
+	resp, err := e.doRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, unexpectedStatus(ctx, resp)
+	}
+
+	body, err := readBody(ctx, resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := checkJSONResponse(resp, body); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, json.Unmarshal(body, v)
+}
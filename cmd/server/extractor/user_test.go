@@ -0,0 +1,67 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestExtractUserAbout(t *testing.T) {
+	var requested string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.String()
+		return cannedResponse(req, http.StatusOK, `{"kind": "t2", "data": {
+			"name": "gopher", "link_karma": 1200, "comment_karma": 3400,
+			"created_utc": 1500000000.0, "is_gold": true, "is_mod": false
+		}}`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	user, err := e.ExtractUserAbout(context.Background(), "gopher")
+	if err != nil {
+		t.Fatalf("ExtractUserAbout failed: %v", err)
+	}
+	if want := "https://www.reddit.com/user/gopher/about.json"; requested != want {
+		t.Errorf("requested %q, want %q", requested, want)
+	}
+	if user.LinkKarma != 1200 || user.CommentKarma != 3400 || !user.IsGold || user.IsMod || user.Suspended {
+		t.Errorf("unexpected user: %+v", user)
+	}
+	if user.CreatedTime == "" {
+		t.Error("expected created_time to be set")
+	}
+}
+
+func TestExtractUserAboutSuspended(t *testing.T) {
+	e := fixtureExtractor(`{"kind": "t2", "data": {"name": "banned_user", "is_suspended": true}}`)
+
+	user, err := e.ExtractUserAbout(context.Background(), "banned_user")
+	if err != nil {
+		t.Fatalf("ExtractUserAbout failed: %v", err)
+	}
+	if !user.Suspended || user.Name != "banned_user" || user.LinkKarma != 0 {
+		t.Errorf("unexpected user: %+v", user)
+	}
+}
+
+func TestExtractUserAboutNotFound(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return cannedResponse(req, http.StatusNotFound, `{"message": "Not Found", "error": 404}`), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client))
+
+	if _, err := e.ExtractUserAbout(context.Background(), "nobody_here"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestExtractUserAboutInvalidUsername(t *testing.T) {
+	for _, name := range []string{"", "ab", "../etc", "has space", "waytoolongusername_over20"} {
+		_, err := mustNewExtractor().ExtractUserAbout(context.Background(), name)
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%q: err = %v, want ValidationError", name, err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package extractor
+
+import "context"
+
+type userAgentKey struct{}
+
+// WithUserAgent returns a context carrying ua. Extractions run with it send
+// ua as the User-Agent of their Reddit requests, both to the API and when
+// scraping HTML, in place of the Extractor's defaults and its randomized
+// browser user agents. An empty ua keeps the defaults.
+func WithUserAgent(ctx context.Context, ua string) context.Context {
+	return context.WithValue(ctx, userAgentKey{}, ua)
+}
+
+// UserAgentFromContext returns the user agent set by WithUserAgent, or "".
+func UserAgentFromContext(ctx context.Context) string {
+	ua, _ := ctx.Value(userAgentKey{}).(string)
+	return ua
+}
@@ -0,0 +1,60 @@
+package extractor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUserAgentFromContext(t *testing.T) {
+	if got := UserAgentFromContext(WithUserAgent(context.Background(), "replay/1.0")); got != "replay/1.0" {
+		t.Fatalf("UserAgentFromContext = %q, want replay/1.0", got)
+	}
+	if got := UserAgentFromContext(context.Background()); got != "" {
+		t.Fatalf("UserAgentFromContext on a plain context = %q, want empty", got)
+	}
+}
+
+func TestWithUserAgentOverridesRequests(t *testing.T) {
+	var htmlUA atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		htmlUA.Store(r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, "<html><body><h1>From HTML</h1></body></html>")
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	postURL := server.URL + "/r/golang/comments/abc123/fixture_post/"
+
+	var apiUA atomic.Value
+	client := &http.Client{Transport: apiTransport(func(req *http.Request) (*http.Response, error) {
+		apiUA.Store(req.Header.Get("User-Agent"))
+		return cannedResponse(req, http.StatusOK, postFixture), nil
+	})}
+	e := mustNewExtractor(WithHTTPClient(client), WithAllowedHosts([]string{u.Hostname()}))
+
+	if _, err := e.ExtractRedditPostWithOptions(context.Background(), postURL, APIOnly()); err != nil {
+		t.Fatalf("API extraction failed: %v", err)
+	}
+	if got := apiUA.Load(); got != apiUserAgent {
+		t.Errorf("default API User-Agent = %q, want %q", got, apiUserAgent)
+	}
+
+	ctx := WithUserAgent(context.Background(), "replay/1.0")
+	if _, err := e.ExtractRedditPostWithOptions(ctx, postURL, APIOnly()); err != nil {
+		t.Fatalf("API extraction failed: %v", err)
+	}
+	if _, err := e.ExtractRedditPostWithOptions(ctx, postURL, HTMLOnly()); err != nil {
+		t.Fatalf("HTML extraction failed: %v", err)
+	}
+	if got := apiUA.Load(); got != "replay/1.0" {
+		t.Errorf("API User-Agent = %q, want the context override", got)
+	}
+	if got := htmlUA.Load(); got != "replay/1.0" {
+		t.Errorf("HTML User-Agent = %q, want the context override", got)
+	}
+}
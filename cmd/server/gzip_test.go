@@ -0,0 +1,96 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func gzipTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gzipMiddleware())
+	router.GET("/large", func(c *gin.Context) {
+		renderJSON(c, http.StatusOK, apiResponse{Success: true, Data: strings.Repeat("comment ", 10000)})
+	})
+	router.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "application/octet-stream", []byte("already compressed"))
+	})
+	return router
+}
+
+func TestGzipMiddlewareCompresses(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	rec := httptest.NewRecorder()
+	gzipTestRouter().ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.Body.Len() >= 80000 {
+		t.Errorf("body is %d bytes, expected it compressed", rec.Body.Len())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading gzip body: %v", err)
+	}
+	if !strings.HasPrefix(string(body), `{"success":true,"data":"comment comment`) {
+		t.Errorf("unexpected body %.60q", body)
+	}
+}
+
+func TestGzipMiddlewarePassThrough(t *testing.T) {
+	router := gzipTestRouter()
+
+	for _, encoding := range []string{"", "identity", "gzip;q=0"} {
+		req := httptest.NewRequest(http.MethodGet, "/large", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Accept-Encoding %q: response was compressed", encoding)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/encoded", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Body.String() != "already compressed" {
+		t.Errorf("body = %q, want it passed through uncompressed", rec.Body.String())
+	}
+}
+
+func TestGzipKeepsCORSVary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(corsMiddleware([]string{"https://app.example.com"}), gzipMiddleware())
+	router.GET("/large", func(c *gin.Context) {
+		renderJSON(c, http.StatusOK, apiResponse{Success: true, Data: strings.Repeat("comment ", 10000)})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	vary := strings.Join(rec.Header().Values("Vary"), ", ")
+	if !strings.Contains(vary, "Origin") || !strings.Contains(vary, "Accept-Encoding") {
+		t.Errorf("Vary = %q, want both Origin and Accept-Encoding", vary)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+const (
+	// exportTimeout bounds a single background export, including all pages.
+	exportTimeout = 10 * time.Minute
+	// maxExportPosts caps the count accepted by the export endpoint.
+	maxExportPosts = 5000
+	// jobRetention is how long finished jobs stay queryable.
+	jobRetention = time.Hour
+	// callbackTimeout bounds the webhook POST made when a job finishes.
+	callbackTimeout = 30 * time.Second
+)
+
+const (
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+)
+
+type exportRequest struct {
+	URL         string `json:"url"`
+	Sort        string `json:"sort"`
+	TimeRange   string `json:"time_range"`
+	Count       int    `json:"count"`
+	CallbackURL string `json:"callback_url"`
+}
+
+// exportJob is the queryable state of one background export.
+type exportJob struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	PostCount  int        `json:"post_count"`
+	Error      string     `json:"error,omitempty"`
+	// CallbackError is set when the result could not be delivered to the
+	// callback URL; the export itself may still have succeeded.
+	CallbackError string `json:"callback_error,omitempty"`
+}
+
+// exportCallback is the body POSTed to the callback URL.
+type exportCallback struct {
+	JobID   string                           `json:"job_id"`
+	Success bool                             `json:"success"`
+	Data    *extractor.SubredditListResponse `json:"data,omitempty"`
+	Error   string                           `json:"error,omitempty"`
+}
+
+// jobStore tracks export jobs in memory and caps how many run at once.
+type jobStore struct {
+	mu         sync.Mutex
+	jobs       map[string]*exportJob
+	running    int
+	maxRunning int
+}
+
+func newJobStore(maxRunning int) *jobStore {
+	return &jobStore{
+		jobs:       make(map[string]*exportJob),
+		maxRunning: maxRunning,
+	}
+}
+
+// start registers a new running job, or reports false if the concurrent
+// job cap is reached. Finished jobs past their retention are dropped here.
+func (s *jobStore) start() (*exportJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running >= s.maxRunning {
+		return nil, false
+	}
+	now := time.Now()
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > jobRetention {
+			delete(s.jobs, id)
+		}
+	}
+
+	job := &exportJob{ID: randomID(), Status: jobRunning, CreatedAt: now}
+	s.jobs[job.ID] = job
+	s.running++
+	return job, true
+}
+
+// finish marks a job done and frees its slot. update runs under the lock.
+func (s *jobStore) finish(id string, update func(*exportJob)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+	update(job)
+	now := time.Now()
+	job.FinishedAt = &now
+	s.running--
+}
+
+// get returns a snapshot of the job with the given id.
+func (s *jobStore) get(id string) (exportJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return exportJob{}, false
+	}
+	return *job, true
+}
+
+// errInternalCallback rejects callbacks to the server's own network, which
+// would let API callers reach internal services through it.
+var errInternalCallback = errors.New("callback_url must not point to a loopback, private or link-local address")
+
+// validateCallbackURL requires an absolute http or https URL. Unless
+// allowPrivate is set, every address its host resolves to must also be
+// public; newCallbackClient checks again when connecting, since the host
+// may resolve differently by then.
+func validateCallbackURL(ctx context.Context, raw string, allowPrivate bool) error {
+	if raw == "" {
+		return errors.New("callback_url is required")
+	}
+	u, err := url.ParseRequestURI(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("callback_url must be an absolute http or https url")
+	}
+	if allowPrivate {
+		return nil
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if isInternalIP(ip) {
+			return errInternalCallback
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return errors.New("callback_url host does not resolve")
+	}
+	for _, addr := range addrs {
+		if isInternalIP(addr.IP) {
+			return errInternalCallback
+		}
+	}
+	return nil
+}
+
+// isInternalIP reports whether ip is loopback, private, link-local, such
+// as the 169.254.169.254 cloud metadata address, or unspecified.
+func isInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// newCallbackClient returns the client export callbacks are POSTed with.
+// Unless allowPrivate is set it refuses to connect to internal addresses,
+// and it never follows redirects, which could otherwise lead a public
+// callback host inward; a redirect fails the callback like any other
+// non-2xx status. No proxy is used so the check sees the real peer.
+func newCallbackClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isInternalIP(ip) {
+				return errInternalCallback
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   callbackTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// exportHandler accepts an export job, answers 202 with its id and runs the
+// export in the background, POSTing the result to the callback URL with
+// client. allowPrivate lets the callback URL point to internal addresses,
+// see validateCallbackURL.
+func exportHandler(ext *extractor.Extractor, jobs *jobStore, client *http.Client, allowPrivate bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req exportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   "invalid json body",
+			})
+			return
+		}
+
+		if err := ext.ValidateSubredditURL(req.URL); err != nil {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if req.Count < 1 || req.Count > maxExportPosts {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   fmt.Sprintf("count must be between 1 and %d", maxExportPosts),
+			})
+			return
+		}
+		if err := validateCallbackURL(c.Request.Context(), req.CallbackURL, allowPrivate); err != nil {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		job, ok := jobs.start()
+		if !ok {
+			renderJSON(c, http.StatusServiceUnavailable, apiResponse{
+				Success: false,
+				Error:   "too many export jobs running, retry later",
+			})
+			return
+		}
+
+		go runExport(ext, jobs, client, job.ID, req)
+
+		renderJSON(c, http.StatusAccepted, apiResponse{
+			Success: true,
+			Data:    gin.H{"job_id": job.ID},
+		})
+	}
+}
+
+// runExport performs one export job. It is detached from the request that
+// created it, so it runs under its own timeout.
+func runExport(ext *extractor.Extractor, jobs *jobStore, client *http.Client, id string, req exportRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	resp, err := ext.ExtractSubredditPostsN(ctx, req.URL, req.Sort, req.TimeRange, req.Count)
+	payload := exportCallback{JobID: id, Success: err == nil, Data: resp}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	callbackErr := postCallback(client, req.CallbackURL, payload)
+	if callbackErr != nil {
+		log.Printf("[export] callback failed: job=%s, err=%v", id, callbackErr)
+	}
+
+	jobs.finish(id, func(job *exportJob) {
+		job.Status = jobSucceeded
+		if err != nil {
+			job.Status = jobFailed
+			job.Error = err.Error()
+		}
+		if resp != nil {
+			job.PostCount = len(resp.Posts)
+		}
+		if callbackErr != nil {
+			job.CallbackError = callbackErr.Error()
+		}
+	})
+}
+
+func postCallback(client *http.Client, callbackURL string, payload exportCallback) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callbackTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected callback status: %s", resp.Status)
+	}
+	return nil
+}
+
+// jobStatusHandler reports the state of an export job.
+func jobStatusHandler(jobs *jobStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := jobs.get(c.Param("id"))
+		if !ok {
+			renderJSON(c, http.StatusNotFound, apiResponse{
+				Success: false,
+				Error:   "job not found",
+			})
+			return
+		}
+		renderJSON(c, http.StatusOK, apiResponse{
+			Success: true,
+			Data:    job,
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+const listingFixture = `{"kind": "Listing", "data": {"after": null, "children": [
+	{"kind": "t3", "data": {"id": "aaa", "title": "a", "permalink": "/r/golang/comments/aaa/a/"}},
+	{"kind": "t3", "data": {"id": "bbb", "title": "b", "permalink": "/r/golang/comments/bbb/b/"}}
+]}}`
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// fixtureExtractor returns an Extractor that answers every Reddit request
+// with body instead of touching the network.
+func fixtureExtractor(body string) *extractor.Extractor {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})}
+	ext, err := extractor.NewExtractor(extractor.WithHTTPClient(client))
+	if err != nil {
+		panic(err)
+	}
+	return ext
+}
+
+func TestExportJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	callbacks := make(chan exportCallback, 1)
+	callbackSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload exportCallback
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode callback: %v", err)
+		}
+		callbacks <- payload
+	}))
+	defer callbackSrv.Close()
+
+	jobs := newJobStore(1)
+	router := gin.New()
+	router.POST("/api/subreddit/export", exportHandler(fixtureExtractor(listingFixture), jobs, callbackSrv.Client(), true))
+	router.GET("/api/jobs/:id", jobStatusHandler(jobs))
+
+	body := `{"url": "https://www.reddit.com/r/golang/", "count": 10, "callback_url": "` + callbackSrv.URL + `"}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/subreddit/export", strings.NewReader(body)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	var accepted struct {
+		Data struct {
+			JobID string `json:"job_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &accepted); err != nil || accepted.Data.JobID == "" {
+		t.Fatalf("missing job id in %s", rec.Body)
+	}
+
+	select {
+	case payload := <-callbacks:
+		if !payload.Success || payload.JobID != accepted.Data.JobID || len(payload.Data.Posts) != 2 {
+			t.Errorf("unexpected callback payload: %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not called")
+	}
+
+	// The job is marked finished right after the callback returns.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, ok := jobs.get(accepted.Data.JobID)
+		if ok && job.Status != jobRunning {
+			if job.Status != jobSucceeded || job.PostCount != 2 {
+				t.Errorf("unexpected job state: %+v", job)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/"+accepted.Data.JobID, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), jobSucceeded) {
+		t.Errorf("job status = %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestExportRejectsBadCallbackURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/api/subreddit/export", exportHandler(fixtureExtractor(listingFixture), newJobStore(1), http.DefaultClient, false))
+
+	for _, callbackURL := range []string{
+		"file:///etc/passwd",
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://0.0.0.0/hook",
+	} {
+		body := `{"url": "https://www.reddit.com/r/golang/", "count": 10, "callback_url": "` + callbackURL + `"}`
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/subreddit/export", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", callbackURL, rec.Code)
+		}
+	}
+}
+
+func TestCallbackClientRefusesInternalAddresses(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	if err := postCallback(newCallbackClient(false), srv.URL, exportCallback{JobID: "j"}); !errors.Is(err, errInternalCallback) {
+		t.Errorf("err = %v, want errInternalCallback at dial time", err)
+	}
+	if hits != 0 {
+		t.Errorf("callback server hit %d times", hits)
+	}
+	if err := postCallback(newCallbackClient(true), srv.URL, exportCallback{JobID: "j"}); err != nil {
+		t.Errorf("allowed private callback failed: %v", err)
+	}
+}
+
+func TestCallbackClientDoesNotFollowRedirects(t *testing.T) {
+	var inner int
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner++
+	}))
+	defer target.Close()
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer redirector.Close()
+
+	if err := postCallback(newCallbackClient(true), redirector.URL, exportCallback{JobID: "j"}); err == nil {
+		t.Error("expected a redirected callback to fail")
+	}
+	if inner != 0 {
+		t.Errorf("redirect target hit %d times", inner)
+	}
+}
+
+func TestJobStoreCap(t *testing.T) {
+	jobs := newJobStore(1)
+	job, ok := jobs.start()
+	if !ok {
+		t.Fatal("first job rejected")
+	}
+	if _, ok := jobs.start(); ok {
+		t.Fatal("second job accepted past the cap")
+	}
+	jobs.finish(job.ID, func(job *exportJob) { job.Status = jobSucceeded })
+	if _, ok := jobs.start(); !ok {
+		t.Fatal("job rejected after a slot was freed")
+	}
+}
+
+func TestJobStatusNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/jobs/:id", jobStatusHandler(newJobStore(1)))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+}
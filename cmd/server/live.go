@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+const (
+	// defaultLivePollInterval is used when the client does not pick one.
+	defaultLivePollInterval = 30 * time.Second
+	// minLivePollInterval keeps a single client from hammering Reddit.
+	minLivePollInterval = 10 * time.Second
+	// livePollLimit is the listing size fetched on each poll.
+	livePollLimit = 25
+	// liveSeenLimit bounds the post IDs a connection remembers. Posts
+	// older than that many newer ones have dropped out of the "new"
+	// listing for good.
+	liveSeenLimit = 10 * livePollLimit
+)
+
+// liveHandler serves a WebSocket feed of a subreddit's new posts. It polls
+// the "new" listing every interval and pushes each post not seen before on
+// the connection as a JSON SubredditPost, oldest first. The first poll only
+// records what is already there. Polling goes through ext, so the
+// extractor's request spacing and concurrency limits apply across all
+// connections.
+//
+// Query parameters: url (required) and interval, a Go duration of at least
+// minInterval.
+//
+// Browsers send an Origin with WebSocket handshakes but do not apply CORS to
+// them, so the handshake itself only accepts the server's own origin and
+// those in origins, as corsMiddleware does. Clients sending no Origin, which
+// are not browsers, are accepted.
+func liveHandler(ext *extractor.Extractor, minInterval time.Duration, origins []string) gin.HandlerFunc {
+	allowed := newOriginSet(origins)
+	return func(c *gin.Context) {
+		subredditURL := c.Query("url")
+		if err := ext.ValidateSubredditURL(subredditURL); err != nil {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		interval := defaultLivePollInterval
+		if raw := c.Query("interval"); raw != "" {
+			d, err := time.ParseDuration(raw)
+			if err != nil || d < minInterval {
+				renderJSON(c, http.StatusBadRequest, apiResponse{
+					Success: false,
+					Error:   "interval must be a duration of at least " + minInterval.String(),
+				})
+				return
+			}
+			interval = d
+		}
+		if interval < minInterval {
+			interval = minInterval
+		}
+
+		websocket.Server{
+			Handshake: func(config *websocket.Config, req *http.Request) error {
+				return checkLiveOrigin(config, req, allowed)
+			},
+			Handler: func(ws *websocket.Conn) {
+				defer ws.Close()
+				streamNewPosts(c.Request.Context(), ws, ext, subredditURL, interval)
+			},
+		}.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// checkLiveOrigin accepts a WebSocket handshake without an Origin, from the
+// server's own origin or from one in allowed. A rejected handshake is
+// answered with 403.
+func checkLiveOrigin(config *websocket.Config, req *http.Request, allowed originSet) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	if origin == nil {
+		return nil
+	}
+	config.Origin = origin
+	if strings.EqualFold(origin.Host, req.Host) || allowed.allows(origin.Scheme+"://"+origin.Host) {
+		return nil
+	}
+	return errors.New("websocket origin not allowed")
+}
+
+// streamNewPosts runs the poll loop for one connection until the client
+// disconnects, a write fails or ctx is done.
+func streamNewPosts(ctx context.Context, ws *websocket.Conn, ext *extractor.Extractor, subredditURL string, interval time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The client never sends anything meaningful; reading only serves to
+	// notice when it goes away.
+	go func() {
+		defer cancel()
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	seen := extractor.NewLimitedSeenSet(liveSeenLimit)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for first := true; ; first = false {
+		resp, err := ext.ExtractSubredditPosts(ctx, subredditURL, "new", "", livePollLimit, "", extractor.SkipSeen(seen))
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("[live] poll failed: url=%s, err=%v", subredditURL, err)
+		} else if !first {
+			for i := len(resp.Posts) - 1; i >= 0; i-- {
+				if err := websocket.JSON.Send(ws, resp.Posts[i]); err != nil {
+					return
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+// sequenceExtractor answers the i-th Reddit request with bodies[i], repeating
+// the last body once they run out.
+func sequenceExtractor(bodies ...string) *extractor.Extractor {
+	var calls atomic.Int64
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		i := int(calls.Add(1) - 1)
+		if i >= len(bodies) {
+			i = len(bodies) - 1
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(bodies[i])),
+			Request:    req,
+		}, nil
+	})}
+	ext, err := extractor.NewExtractor(extractor.WithHTTPClient(client))
+	if err != nil {
+		panic(err)
+	}
+	return ext
+}
+
+func TestLiveStreamsOnlyNewPosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	initial := `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"id": "aaa", "title": "a", "permalink": "/r/golang/comments/aaa/a/"}}
+	]}}`
+	later := `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"id": "ccc", "title": "c", "permalink": "/r/golang/comments/ccc/c/"}},
+		{"kind": "t3", "data": {"id": "bbb", "title": "b", "permalink": "/r/golang/comments/bbb/b/"}},
+		{"kind": "t3", "data": {"id": "aaa", "title": "a", "permalink": "/r/golang/comments/aaa/a/"}}
+	]}}`
+
+	router := gin.New()
+	router.GET("/api/subreddit/live", liveHandler(sequenceExtractor(initial, later), time.Millisecond, nil))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/subreddit/live?interval=20ms&url=https://www.reddit.com/r/golang/"
+	ws, err := websocket.Dial(wsURL, "", srv.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer ws.Close()
+
+	var got []string
+	for len(got) < 2 {
+		_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var post extractor.SubredditPost
+		if err := websocket.JSON.Receive(ws, &post); err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		got = append(got, post.ID)
+	}
+	if got[0] != "bbb" || got[1] != "ccc" {
+		t.Fatalf("pushed %v, want [bbb ccc]", got)
+	}
+
+	// Nothing new appears after that.
+	_ = ws.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	var extra extractor.SubredditPost
+	if err := websocket.JSON.Receive(ws, &extra); err == nil {
+		t.Fatalf("unexpected extra post %+v", extra)
+	}
+}
+
+func TestLiveRejectsShortInterval(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/subreddit/live", liveHandler(sequenceExtractor("{}"), minLivePollInterval, nil))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/subreddit/live?interval=1s&url=https://www.reddit.com/r/golang/", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestLiveChecksOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/subreddit/live", liveHandler(sequenceExtractor(`{"kind": "Listing", "data": {"children": []}}`), time.Millisecond, []string{"https://app.example.com"}))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/subreddit/live?interval=1s&url=https://www.reddit.com/r/golang/"
+
+	for origin, wantOK := range map[string]bool{
+		srv.URL:                   true,
+		"https://app.example.com": true,
+		"https://evil.example":    false,
+	} {
+		ws, err := websocket.Dial(wsURL, "", origin)
+		if err == nil {
+			ws.Close()
+		}
+		if (err == nil) != wantOK {
+			t.Errorf("origin %s: dial err = %v, want accepted %v", origin, err, wantOK)
+		}
+	}
+}
@@ -2,10 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,6 +22,38 @@ import (
 
 type extractRequest struct {
 	URL string `json:"url"`
+	// IncludeRaw attaches the upstream Reddit JSON to the response as "raw".
+	// It can be large, so leave it off unless the parsed fields are not enough.
+	IncludeRaw bool `json:"include_raw"`
+	// MaxImages keeps only the first n images of the post; 0 keeps all.
+	MaxImages int `json:"max_images"`
+	// RewritePreviews swaps expiring preview.redd.it image URLs for their
+	// stable i.redd.it equivalent where it can be derived.
+	RewritePreviews bool `json:"rewrite_previews"`
+	// DropStickiedComments and DropAutoModeratorComments remove those
+	// top-level comments, with their replies.
+	DropStickiedComments      bool `json:"drop_stickied_comments"`
+	DropAutoModeratorComments bool `json:"drop_automoderator_comments"`
+	// ExcludeCommentAuthors and OnlyCommentAuthors filter comments by author
+	// at every depth. A dropped comment takes its replies with it unless
+	// KeepOrphanedReplies is set.
+	ExcludeCommentAuthors []string `json:"exclude_comment_authors"`
+	OnlyCommentAuthors    []string `json:"only_comment_authors"`
+	KeepOrphanedReplies   bool     `json:"keep_orphaned_replies"`
+}
+
+type batchExtractRequest struct {
+	URLs []string `json:"urls"`
+	// StopOnError abandons the remaining urls once one fails instead of
+	// reporting a result for each.
+	StopOnError bool `json:"stop_on_error"`
+}
+
+type batchExtractResult struct {
+	URL     string                `json:"url"`
+	Success bool                  `json:"success"`
+	Data    *extractor.RedditPost `json:"data,omitempty"`
+	Error   string                `json:"error,omitempty"`
 }
 
 type subredditListRequest struct {
@@ -23,99 +62,396 @@ type subredditListRequest struct {
 	TimeRange string `json:"time_range"`
 	Limit     int    `json:"limit"`
 	After     string `json:"after"`
+	// IncludeRaw attaches the upstream listing JSON, see extractRequest.
+	IncludeRaw bool `json:"include_raw"`
+	// MaxImages limits image_urls per post, see extractRequest.
+	MaxImages int `json:"max_images"`
+	// RewritePreviews rewrites image_urls, see extractRequest.
+	RewritePreviews bool `json:"rewrite_previews"`
+	// PreferDurableImages lists gallery images by their unexpiring
+	// i.redd.it URL, which may be lower quality than the signed preview.
+	PreferDurableImages bool `json:"prefer_durable_images"`
+	// ImagesOnly drops posts without images and SelfOnly keeps only text
+	// posts; setting both is rejected.
+	ImagesOnly bool `json:"images_only"`
+	SelfOnly   bool `json:"self_only"`
+	// FieldCoverage adds a per-field count of populated posts, a debugging
+	// aid for spotting Reddit schema changes.
+	FieldCoverage bool `json:"field_coverage"`
+	// MinImageWidth and MinImageHeight drop smaller images, and those of
+	// unknown size; DropSmallImagePosts also drops posts left without any.
+	MinImageWidth       int  `json:"min_image_width"`
+	MinImageHeight      int  `json:"min_image_height"`
+	DropSmallImagePosts bool `json:"drop_small_image_posts"`
 }
 
 type apiResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Success bool            `json:"success"`
+	Data    interface{}     `json:"data,omitempty"`
+	Raw     json.RawMessage `json:"raw,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	// ElapsedMS is the upstream extraction time, only set with ?timing=true.
+	ElapsedMS *int64 `json:"elapsed_ms,omitempty"`
 }
 
 func main() {
 	port := flag.Int("port", 8080, "port to listen on")
+	maxBatch := flag.Int("max-batch", 20, "maximum number of urls accepted by the batch extract endpoint")
+	allowPrivateCallbacks := flag.Bool("allow-private-callbacks", false, "let export callback urls point to loopback, private and link-local addresses, for webhooks on the server's own network")
+	maxJobs := flag.Int("max-jobs", 4, "maximum number of subreddit export jobs running at once")
+	minInterval := flag.Duration("min-interval", 0, "minimum spacing between requests to Reddit across all endpoints, e.g. 1s; 0 disables")
+	retries := flag.Int("retries", 0, "times to retry Reddit requests failing with 429, 502, 503, 504 or a network error; 0 disables")
+	retryBudget := flag.Duration("retry-budget", 0, "maximum total time spent retrying a single Reddit request, e.g. 10s; 0 leaves only the request deadline")
+	breakerFailures := flag.Int("breaker-failures", 0, "consecutive failed Reddit requests within -breaker-window that make the server fail fast with 503 for -breaker-cooldown; 0 disables")
+	breakerWindow := flag.Duration("breaker-window", time.Minute, "span within which -breaker-failures failures open the circuit")
+	breakerCooldown := flag.Duration("breaker-cooldown", 30*time.Second, "time the circuit stays open before a probe request is let through")
+	maxRedirects := flag.Int("max-redirects", -1, "redirects a single Reddit request may follow before failing, also failing on redirect loops; 0 follows none, -1 keeps the net/http default of 10")
+	warmup := flag.Duration("warmup", 0, "before serving, open a connection to Reddit for at most this long so the first extraction skips DNS and TLS setup, e.g. 5s; failures are logged and do not stop startup; 0 disables")
+	traceStdout := flag.Bool("trace-stdout", false, "record OpenTelemetry spans for extractions and print them to stdout")
+	gzipResponses := flag.Bool("gzip", true, "gzip-compress responses for clients that accept it")
+	defaultLimit := flag.Int("default-limit", envInt("DEFAULT_LIMIT", 20), "subreddit posts returned when a request gives no limit; defaults to $DEFAULT_LIMIT")
+	maxLimit := flag.Int("max-limit", envInt("MAX_LIMIT", 100), "largest subreddit limit accepted, at most 100; defaults to $MAX_LIMIT")
+	rssFallback := flag.Bool("rss-fallback", false, "serve subreddit listings from the RSS feed, with fewer fields, when the JSON API is blocked")
+	strictValidation := flag.Bool("strict-validation", false, "reject subreddit requests without an explicit sort and limit instead of defaulting them")
+	defaultSort := flag.String("default-sort", os.Getenv("DEFAULT_SORT"), "subreddit sort used when a request gives none: hot, new, top or rising; defaults to $DEFAULT_SORT, empty means hot")
+	apiKeys := flag.String("api-keys", os.Getenv("API_KEYS"), "comma-separated keys required on /api endpoints as a Bearer token or X-API-Key header; defaults to $API_KEYS, empty leaves the API open")
+	corsOrigins := flag.String("cors-origins", os.Getenv("CORS_ORIGINS"), "comma-separated origins allowed to call the API from a browser, or * for any; defaults to $CORS_ORIGINS, empty means same-origin only")
 	flag.Parse()
 
+	var opts []extractor.Option
+	shutdownTracing := func(context.Context) error { return nil }
+	if *traceStdout {
+		shutdown, err := setupTracing()
+		if err != nil {
+			log.Fatalf("tracing setup failed: %v", err)
+		}
+		shutdownTracing = shutdown
+		opts = append(opts, extractor.WithTracer(newOtelTracer()))
+	}
+	if *minInterval > 0 {
+		opts = append(opts, extractor.WithMinInterval(*minInterval))
+	}
+	if *retries > 0 {
+		opts = append(opts, extractor.WithRetry(*retries), extractor.WithRetryBudget(*retryBudget))
+	}
+	if *maxRedirects >= 0 {
+		opts = append(opts, extractor.WithMaxRedirects(*maxRedirects))
+	}
+	if *breakerFailures > 0 {
+		opts = append(opts, extractor.WithCircuitBreaker(*breakerFailures, *breakerWindow, *breakerCooldown))
+	}
+	opts = append(opts, extractor.WithSubredditLimits(*defaultLimit, *maxLimit), extractor.WithDefaultSort(*defaultSort), extractor.WithStrictValidation(*strictValidation), extractor.WithFallbackToRSS(*rssFallback))
+	ext, err := extractor.NewExtractor(opts...)
+	if err != nil {
+		log.Fatalf("extractor setup failed: %v", err)
+	}
+	if *warmup > 0 {
+		warmupReddit(ext, *warmup)
+	}
+
 	router := gin.Default()
+	router.Use(requestIDMiddleware(), traceContextMiddleware())
+	if origins := parseList(*corsOrigins); len(origins) > 0 {
+		router.Use(corsMiddleware(origins))
+	}
+	if *gzipResponses {
+		router.Use(gzipMiddleware())
+	}
+
+	router.GET("/healthz", func(c *gin.Context) {
+		renderJSON(c, http.StatusOK, apiResponse{Success: true})
+	})
 
-	router.POST("/api/reddit/extract", func(c *gin.Context) {
+	api := router.Group("", apiKeyMiddleware(parseList(*apiKeys)))
+
+	api.POST("/api/reddit/extract", func(c *gin.Context) {
 		var req extractRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, apiResponse{
+			renderJSON(c, http.StatusBadRequest, apiResponse{
 				Success: false,
 				Error:   "invalid json body",
 			})
 			return
 		}
 
-		if err := extractor.ValidateRedditURL(req.URL); err != nil {
-			c.JSON(http.StatusBadRequest, apiResponse{
+		if err := ext.ValidateRedditURL(req.URL); err != nil {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
 				Success: false,
 				Error:   err.Error(),
 			})
 			return
 		}
+		if req.MaxImages < 0 {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   "max_images must not be negative",
+			})
+			return
+		}
 
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
 		defer cancel()
 
-		post, err := extractor.ExtractRedditPost(ctx, req.URL)
+		var raw *extractor.RawResponse
+		if req.IncludeRaw {
+			ctx, raw = extractor.WithRawResponse(ctx)
+		}
+
+		opts := []extractor.ExtractOption{extractor.MaxImages(req.MaxImages)}
+		if req.RewritePreviews {
+			opts = append(opts, extractor.RewritePreviewImages())
+		}
+		if req.DropStickiedComments {
+			opts = append(opts, extractor.DropStickiedComments())
+		}
+		if req.DropAutoModeratorComments {
+			opts = append(opts, extractor.DropAutoModeratorComments())
+		}
+		if len(req.ExcludeCommentAuthors) > 0 {
+			opts = append(opts, extractor.ExcludeCommentAuthors(req.ExcludeCommentAuthors...))
+		}
+		if len(req.OnlyCommentAuthors) > 0 {
+			opts = append(opts, extractor.OnlyCommentAuthors(req.OnlyCommentAuthors...))
+		}
+		if req.KeepOrphanedReplies {
+			opts = append(opts, extractor.KeepOrphanedReplies())
+		}
+
+		start := time.Now()
+		post, err := ext.ExtractRedditPostWithOptions(ctx, req.URL, opts...)
+		recordElapsed(c, start)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, apiResponse{
+			var validationErr extractor.ValidationError
+			if errors.As(err, &validationErr) {
+				renderJSON(c, http.StatusBadRequest, apiResponse{
+					Success: false,
+					Error:   validationErr.Error(),
+				})
+				return
+			}
+			renderJSON(c, upstreamErrorStatus(c, err), apiResponse{
 				Success: false,
 				Error:   err.Error(),
 			})
 			return
 		}
 
-		c.JSON(http.StatusOK, apiResponse{
+		out := apiResponse{
+			Success: true,
+			Data:    postData(c, post),
+		}
+		if raw != nil {
+			out.Raw = raw.JSON()
+		}
+		renderJSON(c, http.StatusOK, out)
+	})
+
+	api.GET("/api/reddit/comments", commentsHandler(ext))
+
+	api.POST("/api/reddit/extract/batch", func(c *gin.Context) {
+		var req batchExtractRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   "invalid json body",
+			})
+			return
+		}
+
+		if len(req.URLs) == 0 {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   "urls is required",
+			})
+			return
+		}
+		if len(req.URLs) > *maxBatch {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   fmt.Sprintf("at most %d urls allowed per batch", *maxBatch),
+			})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		var opts []extractor.ExtractOption
+		if req.StopOnError {
+			opts = append(opts, extractor.StopOnError())
+		}
+		start := time.Now()
+		results := ext.ExtractRedditPosts(ctx, req.URLs, opts...)
+		recordElapsed(c, start)
+		out := make([]batchExtractResult, len(results))
+		for i, res := range results {
+			out[i] = batchExtractResult{
+				URL:     res.URL,
+				Success: res.Err == nil,
+				Data:    res.Post,
+			}
+			if res.Err != nil {
+				out[i].Error = res.Err.Error()
+			}
+		}
+
+		renderJSON(c, http.StatusOK, apiResponse{
 			Success: true,
-			Data:    post,
+			Data:    out,
 		})
 	})
 
-	router.POST("/api/subreddit/posts", func(c *gin.Context) {
+	api.POST("/api/subreddit/posts", func(c *gin.Context) {
 		var req subredditListRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, apiResponse{
+			renderJSON(c, http.StatusBadRequest, apiResponse{
 				Success: false,
 				Error:   "invalid json body",
 			})
 			return
 		}
 
-		if err := extractor.ValidateSubredditURL(req.URL); err != nil {
-			c.JSON(http.StatusBadRequest, apiResponse{
+		if err := ext.ValidateSubredditURL(req.URL); err != nil {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
 				Success: false,
 				Error:   err.Error(),
 			})
 			return
 		}
+		if req.MaxImages < 0 {
+			renderJSON(c, http.StatusBadRequest, apiResponse{
+				Success: false,
+				Error:   "max_images must not be negative",
+			})
+			return
+		}
 
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
 		defer cancel()
 
-		resp, err := extractor.ExtractSubredditPosts(ctx, req.URL, req.Sort, req.TimeRange, req.Limit, req.After)
+		var raw *extractor.RawResponse
+		if req.IncludeRaw {
+			ctx, raw = extractor.WithRawResponse(ctx)
+		}
+
+		opts := []extractor.ExtractOption{extractor.MaxImages(req.MaxImages)}
+		if req.ImagesOnly {
+			opts = append(opts, extractor.ImagesOnly())
+		}
+		if req.SelfOnly {
+			opts = append(opts, extractor.SelfOnly())
+		}
+		if req.FieldCoverage {
+			opts = append(opts, extractor.ReportFieldCoverage())
+		}
+		if req.RewritePreviews {
+			opts = append(opts, extractor.RewritePreviewImages())
+		}
+		if req.PreferDurableImages {
+			opts = append(opts, extractor.PreferDurableImages())
+		}
+		if req.MinImageWidth > 0 || req.MinImageHeight > 0 {
+			opts = append(opts, extractor.MinImageSize(req.MinImageWidth, req.MinImageHeight, req.DropSmallImagePosts))
+		}
+
+		start := time.Now()
+		resp, err := ext.ExtractSubredditPosts(ctx, req.URL, req.Sort, req.TimeRange, req.Limit, req.After, opts...)
+		recordElapsed(c, start)
 		if err != nil {
 			var validationErr extractor.ValidationError
 			if errors.As(err, &validationErr) {
-				c.JSON(http.StatusBadRequest, apiResponse{
+				renderJSON(c, http.StatusBadRequest, apiResponse{
 					Success: false,
 					Error:   validationErr.Error(),
 				})
 				return
 			}
-			c.JSON(http.StatusInternalServerError, apiResponse{
+			renderJSON(c, upstreamErrorStatus(c, err), apiResponse{
 				Success: false,
 				Error:   err.Error(),
 			})
 			return
 		}
 
-		c.JSON(http.StatusOK, apiResponse{
+		out := apiResponse{
 			Success: true,
 			Data:    resp,
-		})
+		}
+		if raw != nil {
+			out.Raw = raw.JSON()
+		}
+		renderJSON(c, http.StatusOK, out)
 	})
 
-	_ = router.Run(fmt.Sprintf(":%d", *port))
+	jobs := newJobStore(*maxJobs)
+	api.POST("/api/subreddit/export", exportHandler(ext, jobs, newCallbackClient(*allowPrivateCallbacks), *allowPrivateCallbacks))
+	api.GET("/api/jobs/:id", jobStatusHandler(jobs))
+	api.GET("/api/subreddit/live", liveHandler(ext, minLivePollInterval, parseList(*corsOrigins)))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", *port), Handler: router}
+	if err := serve(ctx, srv); err != nil {
+		log.Printf("[server] stopped: err=%v", err)
+	}
+	// Flush the spans still batched for export.
+	flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Printf("[tracing] shutdown failed: err=%v", err)
+	}
+}
+
+// shutdownTimeout bounds how long the server waits for in-flight requests,
+// and then for the span export, once it is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
+// serve runs srv until it fails or ctx is done, and in the latter case
+// shuts it down gracefully, letting in-flight requests finish for up to
+// shutdownTimeout.
+func serve(ctx context.Context, srv *http.Server) error {
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	log.Printf("[server] shutting down: timeout=%v", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// warmupReddit runs ext.Warmup for at most timeout and logs the outcome.
+func warmupReddit(ext *extractor.Extractor, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	if err := ext.Warmup(ctx); err != nil {
+		log.Printf("[warmup] failed: elapsed=%v, err=%v", time.Since(start).Round(time.Millisecond), err)
+		return
+	}
+	log.Printf("[warmup] success: elapsed=%v", time.Since(start).Round(time.Millisecond))
+}
+
+// parseList splits a comma-separated flag value, dropping blank entries.
+func parseList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envInt returns the integer environment variable name, or def when it is
+// unset or not a number.
+func envInt(name string, def int) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return n
 }
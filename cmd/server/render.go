@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+// elapsedKey is the gin context key recordElapsed stores the extraction time
+// under.
+const elapsedKey = "extractElapsed"
+
+// recordElapsed records the time since start, taken just before the
+// handler's extractor call, for the timing query parameter of renderJSON.
+func recordElapsed(c *gin.Context, start time.Time) {
+	c.Set(elapsedKey, time.Since(start))
+}
+
+// renderJSON writes resp honouring three query parameters:
+//
+//   - pretty=true indents the output; the default is compact.
+//   - envelope=false writes only resp.Data on success, so the post or listing
+//     is the top-level object. Errors, and the raw upstream JSON, keep the
+//     envelope, since they have nowhere else to go. The default is true.
+//   - timing=true adds elapsed_ms, the time spent in the extractor call, to
+//     the envelope of handlers that recorded it. The default is false.
+//
+// Values strconv.ParseBool does not accept fall back to the defaults.
+func renderJSON(c *gin.Context, status int, resp apiResponse) {
+	if elapsed, ok := c.Get(elapsedKey); ok && queryBool(c, "timing", false) {
+		ms := elapsed.(time.Duration).Milliseconds()
+		resp.ElapsedMS = &ms
+	}
+	var body interface{} = resp
+	if resp.Success && resp.Raw == nil && !queryBool(c, "envelope", true) {
+		body = resp.Data
+	}
+	if queryBool(c, "pretty", false) {
+		c.IndentedJSON(status, body)
+		return
+	}
+	c.JSON(status, body)
+}
+
+// flatPost is a post with its comment tree replaced by the flat list of
+// extractor.FlattenComments; the outer Comments field shadows the post's.
+type flatPost struct {
+	*extractor.RedditPost
+	Comments []extractor.FlatComment `json:"comments"`
+}
+
+// postData returns post as response data, with its comments flattened when
+// the comments query parameter is "flat". Any other value keeps the tree.
+func postData(c *gin.Context, post *extractor.RedditPost) interface{} {
+	if c.Query("comments") != "flat" {
+		return post
+	}
+	return flatPost{RedditPost: post, Comments: extractor.FlattenComments(post.Comments)}
+}
+
+// upstreamErrorStatus returns the status for a failed extraction: 503 with a
+// Retry-After header while the circuit breaker is open, 500 otherwise.
+func upstreamErrorStatus(c *gin.Context, err error) int {
+	var open extractor.CircuitOpenError
+	if !errors.As(err, &open) {
+		return http.StatusInternalServerError
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(open.RetryAfter.Seconds()))))
+	return http.StatusServiceUnavailable
+}
+
+// queryBool returns the boolean query parameter key, or def when it is
+// missing or malformed.
+func queryBool(c *gin.Context, key string, def bool) bool {
+	v, err := strconv.ParseBool(c.Query(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+func TestRenderJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/ok", func(c *gin.Context) {
+		renderJSON(c, http.StatusOK, apiResponse{Success: true, Data: gin.H{"title": "t"}})
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		renderJSON(c, http.StatusBadRequest, apiResponse{Success: false, Error: "bad"})
+	})
+	router.GET("/timed", func(c *gin.Context) {
+		recordElapsed(c, time.Now().Add(-1500*time.Millisecond))
+		renderJSON(c, http.StatusOK, apiResponse{Success: true, Data: gin.H{"title": "t"}})
+	})
+
+	cases := []struct {
+		path, want string
+	}{
+		{"/ok", `{"success":true,"data":{"title":"t"}}`},
+		{"/ok?pretty=false", `{"success":true,"data":{"title":"t"}}`},
+		{"/ok?pretty=nope", `{"success":true,"data":{"title":"t"}}`},
+		{"/ok?envelope=false", `{"title":"t"}`},
+		{"/ok?pretty=true&envelope=false", "{\n    \"title\": \"t\"\n}"},
+		{"/fail?envelope=false", `{"success":false,"error":"bad"}`},
+		{"/ok?timing=true", `{"success":true,"data":{"title":"t"}}`},
+		{"/timed", `{"success":true,"data":{"title":"t"}}`},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if got := strings.TrimSpace(rec.Body.String()); got != tc.want {
+			t.Errorf("%s: body = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestRenderJSONTiming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/timed", func(c *gin.Context) {
+		recordElapsed(c, time.Now().Add(-1500*time.Millisecond))
+		renderJSON(c, http.StatusOK, apiResponse{Success: true})
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/timed?timing=true", nil))
+	var body struct {
+		ElapsedMS *int64 `json:"elapsed_ms"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body.ElapsedMS == nil || *body.ElapsedMS < 1500 || *body.ElapsedMS > 2000 {
+		t.Errorf("elapsed_ms = %v, want about 1500 in %s", body.ElapsedMS, rec.Body.String())
+	}
+}
+
+func TestPostDataFlatComments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := &extractor.RedditPost{Title: "t", Comments: []extractor.Comment{
+		{ID: "a", Replies: []extractor.Comment{{ID: "b"}}},
+	}}
+	router := gin.New()
+	router.GET("/post", func(c *gin.Context) {
+		renderJSON(c, http.StatusOK, apiResponse{Success: true, Data: postData(c, post)})
+	})
+
+	cases := []struct {
+		path, want string
+	}{
+		{"/post?envelope=false", `"comments":[{"id":"a","body":"","score":0,"replies":[{"id":"b","body":"","score":0}]}]`},
+		{"/post?envelope=false&comments=flat", `"comments":[{"id":"a","body":"","score":0,"depth":0,"parent_index":-1},{"id":"b","body":"","score":0,"depth":1,"parent_index":0}]`},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if got := rec.Body.String(); !strings.Contains(got, tc.want) {
+			t.Errorf("%s: body = %s, want it to contain %s", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestUpstreamErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		err        error
+		status     int
+		retryAfter string
+	}{
+		{errors.New("boom"), http.StatusInternalServerError, ""},
+		{fmt.Errorf("fetch: %w", extractor.CircuitOpenError{RetryAfter: 1500 * time.Millisecond}), http.StatusServiceUnavailable, "2"},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		if got := upstreamErrorStatus(c, tc.err); got != tc.status {
+			t.Errorf("%v: status = %d, want %d", tc.err, got, tc.status)
+		}
+		if got := rec.Header().Get("Retry-After"); got != tc.retryAfter {
+			t.Errorf("%v: Retry-After = %q, want %q", tc.err, got, tc.retryAfter)
+		}
+	}
+}
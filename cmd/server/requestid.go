@@ -0,0 +1,39 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDRE limits accepted incoming IDs to something safe to log.
+var requestIDRE = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestIDMiddleware tags each request with an ID, taken from the
+// X-Request-ID header when the caller sent a usable one and generated
+// otherwise. The ID is echoed in the response header and carried in the
+// request context, where the extractor picks it up for its log lines.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !requestIDRE.MatchString(id) {
+			id = randomID()
+		}
+		c.Header(requestIDHeader, id)
+		c.Request = c.Request.WithContext(extractor.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// randomID returns 16 random hex characters.
+func randomID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gocolly/colly/v2/cmd/server/extractor"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var seen string
+	router := gin.New()
+	router.Use(requestIDMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		seen = extractor.RequestIDFromContext(c.Request.Context())
+	})
+
+	cases := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "generated", incoming: ""},
+		{name: "incoming kept", incoming: "trace-42.a_b", keep: true},
+		{name: "unsafe incoming replaced", incoming: "bad id\nwith newline"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(requestIDHeader, tc.incoming)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(requestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("header = %q, context = %q", got, seen)
+			}
+			if tc.keep != (got == tc.incoming) {
+				t.Errorf("header = %q, incoming = %q, keep = %v", got, tc.incoming, tc.keep)
+			}
+		})
+	}
+}